@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/analytics"
+)
+
+type AnalyticsController struct {
+	aggregates *analytics.Aggregates
+}
+
+func NewAnalyticsController(a *analytics.Aggregates) *AnalyticsController {
+	newAnalyticsController := &AnalyticsController{
+		aggregates: a,
+	}
+	return newAnalyticsController
+}
+
+func (ac *AnalyticsController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"GET /analytics/points",
+		middleware.LogRoute(ac.pointsHandler),
+	)
+}
+
+type pointsReportResponse struct {
+	GroupBy analytics.GroupBy `json:"groupBy"`
+	From    string            `json:"from,omitempty"`
+	To      string            `json:"to,omitempty"`
+	Groups  []analytics.Group `json:"groups"`
+}
+
+func (ac *AnalyticsController) pointsHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := analytics.Query{
+		GroupBy: analytics.GroupBy(params.Get("groupBy")),
+		From:    params.Get("from"),
+		To:      params.Get("to"),
+	}
+
+	groups, err := ac.aggregates.Points(query)
+	if errors.Is(err, analytics.ErrInvalidQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Print(err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pointsReportResponse{
+		GroupBy: query.GroupBy,
+		From:    query.From,
+		To:      query.To,
+		Groups:  groups,
+	})
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/data/analytics"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+func TestAnalyticsPoints(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptRepo.EnableOutbox()
+	receiptController := NewReceiptController(receiptRepo)
+
+	aggregates := analytics.NewAggregates()
+	broker := events.NewBroker()
+	broker.Subscribe(aggregates.HandleEvent)
+
+	for _, name := range []string{"pass1", "pass2", "pass1"} {
+		testCase, err := loadReceiptTestCase(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCorrectPoints(t, receiptController, testCase)
+	}
+
+	if err := events.NewRelay(receiptRepo, broker, 0).Drain(); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	NewAnalyticsController(aggregates).AddRouteHandlers(mux)
+
+	res := serveAdminRequest(mux, "GET", "/analytics/points?groupBy=retailer&from=2022-01-01&to=2022-03-20", "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("Wrong status: '%d' expected '%d': %s", res.Code, http.StatusOK, res.Body.String())
+	}
+
+	var report pointsReportResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	// The M&M Corner Market receipt is on the exclusive end date.
+	if len(report.Groups) != 1 {
+		t.Fatalf("Wrong number of groups: '%d' expected '%d'", len(report.Groups), 1)
+	}
+
+	group := report.Groups[0]
+	if group.Key != "Target" || group.Count != 2 || group.Points != 96 || group.Total != 70.70 {
+		t.Fatalf("Wrong group: %+v", group)
+	}
+
+	for _, path := range []string{
+		"/analytics/points",
+		"/analytics/points?groupBy=month",
+		"/analytics/points?groupBy=day&from=yesterday",
+	} {
+		if res := serveAdminRequest(mux, "GET", path, ""); res.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status for '%s': '%d' expected '%d'", path, res.Code, http.StatusBadRequest)
+		}
+	}
+}
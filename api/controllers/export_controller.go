@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/export"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+)
+
+type ExportController struct {
+	receiptRepository repositories.ReceiptRepository
+}
+
+func NewExportController(rr repositories.ReceiptRepository) *ExportController {
+	newExportController := &ExportController{
+		receiptRepository: rr,
+	}
+	return newExportController
+}
+
+func (ec *ExportController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"GET /admin/export/{kind}",
+		middleware.LogRoute(ec.exportHandler),
+	)
+}
+
+// parseExportFilter reads the retailer and purchase time bounds from the
+// query parameters.
+func parseExportFilter(params url.Values) (repositories.ReceiptFilter, error) {
+	filter := repositories.ReceiptFilter{
+		RetailerId: params.Get("retailerId"),
+		Retailer:   params.Get("retailer"),
+	}
+
+	bounds := []struct {
+		param string
+		time  *time.Time
+	}{
+		{"purchasedFrom", &filter.PurchasedFrom},
+		{"purchasedTo", &filter.PurchasedTo},
+	}
+
+	for _, b := range bounds {
+		if value := params.Get(b.param); value != "" {
+			t, err := export.ParseTime(value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid '%s': %w", b.param, err)
+			}
+			*b.time = t
+		}
+	}
+
+	return filter, nil
+}
+
+func (ec *ExportController) exportHandler(w http.ResponseWriter, r *http.Request) {
+	kind, err := export.ParseKind(r.PathValue("kind"))
+	if err != nil {
+		http.Error(w, "Unknown export; use 'receipts' or 'items'", http.StatusNotFound)
+		return
+	}
+
+	filter, err := parseExportFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Export error: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.csv\"", kind))
+	w.Header().Set("Trailer", export.ERROR_TRAILER)
+
+	ew := &exportResponseWriter{ResponseWriter: w}
+	if err := export.Write(ew, ec.receiptRepository, kind, filter); err != nil {
+		if !ew.written {
+			w.Header().Del("Content-Disposition")
+			w.Header().Del("Trailer")
+			writeRepositoryError(w, err)
+			return
+		}
+
+		// The status has already been sent, so report the failure in the
+		// trailer rather than let a truncated file look complete.
+		log.Printf("Export failed: %s\n", err.Error())
+		w.Header().Set(export.ERROR_TRAILER, err.Error())
+	}
+}
+
+// exportResponseWriter remembers whether any of the export has been sent.
+type exportResponseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *exportResponseWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/export"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+func TestExport(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptController := NewReceiptController(receiptRepo)
+
+	for _, name := range []string{"pass1", "pass2"} {
+		testCase, err := loadReceiptTestCase(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCorrectPoints(t, receiptController, testCase)
+	}
+
+	mux := http.NewServeMux()
+	NewExportController(receiptRepo).AddRouteHandlers(mux)
+
+	cases := []struct {
+		path string
+		rows int
+	}{
+		{"/admin/export/receipts", 3},
+		{"/admin/export/receipts?retailer=target", 2},
+		{"/admin/export/receipts?purchasedFrom=2022-02-01&purchasedTo=2022-04-01T00:00:00Z", 2},
+		{"/admin/export/items", 10},
+		{"/admin/export/items?retailerId=unknown", 1},
+	}
+
+	for _, c := range cases {
+		res := serveAdminRequest(mux, "GET", c.path, "")
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: wrong status: '%d' expected '%d': %s", c.path, res.Code, http.StatusOK, res.Body.String())
+		}
+
+		if contentType := res.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/csv") {
+			t.Fatalf("%s: wrong content type: '%s'", c.path, contentType)
+		}
+
+		rows, err := csv.NewReader(res.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rows) != c.rows {
+			t.Fatalf("%s: wrong number of rows: '%d' expected '%d'", c.path, len(rows), c.rows)
+		}
+	}
+
+	res := serveAdminRequest(mux, "GET", "/admin/export/receipts?retailer=target", "")
+	rows, _ := csv.NewReader(res.Body).ReadAll()
+	if rows[1][1] != "Target" || rows[1][12] != "35.35" || rows[1][13] != "48" {
+		t.Fatalf("Wrong receipt row: %q", rows[1])
+	}
+
+	if res := serveAdminRequest(mux, "GET", "/admin/export/payments", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("Wrong status for unknown export: '%d' expected '%d'", res.Code, http.StatusNotFound)
+	}
+
+	if res := serveAdminRequest(mux, "GET", "/admin/export/items?purchasedTo=soon", ""); res.Code != http.StatusBadRequest {
+		t.Fatalf("Wrong status for invalid time: '%d' expected '%d'", res.Code, http.StatusBadRequest)
+	}
+
+	mux = http.NewServeMux()
+	NewExportController(failingReceiptRepository{repositories.ErrUnavailable}).AddRouteHandlers(mux)
+
+	res = serveAdminRequest(mux, "GET", "/admin/export/receipts", "")
+	if res.Code != http.StatusServiceUnavailable || res.Header().Get("Content-Disposition") != "" {
+		t.Fatalf("Wrong response when storage is unavailable: '%d' %v", res.Code, res.Header())
+	}
+}
+
+// secondPageFailingRepository lists the first page of receipts and fails on
+// the next one.
+type secondPageFailingRepository struct {
+	repositories.ReceiptRepository
+}
+
+func (r secondPageFailingRepository) ListReceipts(
+	filter repositories.ReceiptFilter, after uuid.UUID, limit int,
+) ([]*entities.Receipt, error) {
+	if after != uuid.Nil {
+		return nil, repositories.ErrUnavailable
+	}
+	return r.ReceiptRepository.ListReceipts(filter, after, limit)
+}
+
+func TestExportFailingPartWay(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	for i := 0; i < export.PAGE_SIZE; i++ {
+		if err := receiptRepo.AddReceipt(&entities.Receipt{Id: uuid.New(), Retailer: "Target"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	NewExportController(secondPageFailingRepository{receiptRepo}).AddRouteHandlers(mux)
+
+	res := serveAdminRequest(mux, "GET", "/admin/export/receipts", "").Result()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Wrong status: '%d' expected '%d'", res.StatusCode, http.StatusOK)
+	}
+
+	rows, err := csv.NewReader(res.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first page was sent before the failure.
+	if len(rows) != export.PAGE_SIZE+1 {
+		t.Fatalf("Wrong number of rows: '%d' expected '%d'", len(rows), export.PAGE_SIZE+1)
+	}
+
+	if message := res.Trailer.Get(export.ERROR_TRAILER); !strings.Contains(message, repositories.ErrUnavailable.Error()) {
+		t.Fatalf("Wrong %s trailer: '%s'", export.ERROR_TRAILER, message)
+	}
+}
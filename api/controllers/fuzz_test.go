@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzProcessReceiptHandler(f *testing.F) {
+	fixtures, err := filepath.Glob(filepath.Join(fixtureDir(), "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	// Seed with the request bodies, not the fixture files around them, so
+	// the seeds get past decoding into scoring.
+	for _, fixture := range fixtures {
+		tc, err := loadGoldenCase(fixture)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(fixtureBody(tc))
+	}
+
+	receiptController := makeReceiptController()
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		res := callProcessReceiptHandler(t, receiptController, b)
+
+		// Whatever the client sends, the outcome is a success or a client
+		// error; a 5xx means the input slipped past validation.
+		if res.Code >= http.StatusInternalServerError {
+			t.Fatalf("Status '%d' for body %q: %s", res.Code, b, res.Body.String())
+		}
+	})
+}
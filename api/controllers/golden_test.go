@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/currency"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+	"github.com/vimolicious/receipt-processor/data/transform"
+)
+
+var update = flag.Bool("update", false, "regenerate the expected outputs in test/receipts")
+
+// goldenCase is a fixture in test/receipts. Either Receipt (a JSON receipt)
+// or RawReceipt (a request body that may not be valid JSON) is sent to the
+// process handler and the outcome is compared with the expected fields.
+// Exchange rates come from test/exchange_rates.json.
+type goldenCase struct {
+	Description string          `json:"description,omitempty"`
+	Receipt     json.RawMessage `json:"receipt,omitempty"`
+	RawReceipt  *string         `json:"rawReceipt,omitempty"`
+	goldenExpectation
+}
+
+// goldenExpectation holds the generated outputs. Breakdown is only checked
+// (and regenerated) when the fixture includes it.
+type goldenExpectation struct {
+	Status    int                    `json:"expectedStatus"`
+	Points    *int                   `json:"expectedPoints,omitempty"`
+	Breakdown *[]entities.RulePoints `json:"expectedBreakdown,omitempty"`
+	Error     string                 `json:"expectedError,omitempty"`
+}
+
+func fixtureDir() string {
+	_, currentFile, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(currentFile), "..", "..", "test", "receipts")
+}
+
+// fixtureBody returns the request body a fixture sends.
+func fixtureBody(tc *goldenCase) []byte {
+	if tc.RawReceipt != nil {
+		return []byte(*tc.RawReceipt)
+	}
+	return tc.Receipt
+}
+
+func loadGoldenCase(path string) (*goldenCase, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tc goldenCase
+	if err := json.Unmarshal(contents, &tc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if (tc.Receipt == nil) == (tc.RawReceipt == nil) {
+		return nil, fmt.Errorf("%s: fixture needs exactly one of 'receipt' and 'rawReceipt'", path)
+	}
+
+	return &tc, nil
+}
+
+func runGoldenCase(t *testing.T, tc *goldenCase) goldenExpectation {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptController := NewReceiptController(receiptRepo)
+
+	res := callProcessReceiptHandler(t, receiptController, fixtureBody(tc))
+	actual := goldenExpectation{Status: res.Code}
+
+	if res.Code != http.StatusOK {
+		actual.Error = strings.TrimSpace(res.Body.String())
+		return actual
+	}
+
+	var processResponse processReceiptResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &processResponse); err != nil {
+		t.Fatalf("Couldn't unmarshal process receipt response: '%s'", err.Error())
+	}
+
+	res = callGetPointsHandler(t, receiptController, processResponse.Id)
+	assertStatusCode(t, res, http.StatusOK)
+
+	var pointsResponse getPointsResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &pointsResponse); err != nil {
+		t.Fatalf("Couldn't unmarshal get points response: '%s'", err.Error())
+	}
+	actual.Points = &pointsResponse.Points
+
+	if tc.Breakdown != nil {
+		receipt, err := receiptRepo.ReceiptById(uuid.MustParse(processResponse.Id))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		breakdown := entities.PointsBreakdown(receipt)
+		actual.Breakdown = &breakdown
+	}
+
+	return actual
+}
+
+func TestGoldenReceipts(t *testing.T) {
+	rateTable, err := currency.LoadRateTable(
+		filepath.Join(fixtureDir(), "..", "exchange_rates.json"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform.SetExchangeRates(rateTable)
+	t.Cleanup(func() {
+		transform.SetExchangeRates(currency.NewRateTable(currency.DEFAULT_CURRENCY))
+	})
+
+	fixtures, err := filepath.Glob(filepath.Join(fixtureDir(), "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fixtures) == 0 {
+		t.Fatal("No fixtures found")
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+
+		t.Run(name, func(t *testing.T) {
+			tc, err := loadGoldenCase(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := runGoldenCase(t, tc)
+
+			if *update {
+				tc.goldenExpectation = actual
+
+				var updated bytes.Buffer
+				encoder := json.NewEncoder(&updated)
+				encoder.SetEscapeHTML(false)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(tc); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(fixture, updated.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(tc.goldenExpectation, actual) {
+				expected, _ := json.Marshal(tc.goldenExpectation)
+				got, _ := json.Marshal(actual)
+				t.Fatalf(
+					"Output doesn't match fixture (rerun with -update if intended)\n"+
+						"expected: %s\n     got: %s",
+					expected, got,
+				)
+			}
+		})
+	}
+}
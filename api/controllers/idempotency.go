@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sync"
+)
+
+const IDEMPOTENCY_CACHE_SIZE = 10000
+
+var errIdempotencyKeyReused = errors.New("Idempotency-Key was already used for a different request")
+
+// idempotencyEntry is a request being processed under an Idempotency-Key,
+// or the response it got. done is closed once it's either stored or
+// abandoned.
+type idempotencyEntry struct {
+	bodyHash [sha256.Size]byte
+	response []byte
+	done     chan struct{}
+}
+
+// idempotencyCache remembers the response to each Idempotency-Key so
+// retried requests don't store the receipt twice. Keys are reserved before
+// the request is processed, so a retry that arrives while the original is
+// still running waits for it. Once the cache is full the oldest completed
+// keys are evicted; keys still being processed are kept even if that takes
+// the cache past its capacity.
+type idempotencyCache struct {
+	entries  map[string]*idempotencyEntry
+	keys     []string
+	capacity int
+	mutex    sync.Mutex
+}
+
+func newIdempotencyCache(capacity int) *idempotencyCache {
+	cache := idempotencyCache{
+		entries:  make(map[string]*idempotencyEntry),
+		keys:     make([]string, 0, capacity),
+		capacity: capacity,
+	}
+	return &cache
+}
+
+// reserve claims key for a request with the given body. If the key is new,
+// it returns a pending entry and true, and the caller must complete or
+// release it. Otherwise it waits for the earlier request and returns its
+// entry, or errIdempotencyKeyReused if that request had a different body.
+func (c *idempotencyCache) reserve(key string, body []byte) (*idempotencyEntry, bool, error) {
+	bodyHash := sha256.Sum256(body)
+
+	for {
+		c.mutex.Lock()
+		entry, ok := c.entries[key]
+		if !ok {
+			entry = &idempotencyEntry{bodyHash: bodyHash, done: make(chan struct{})}
+			c.add(key, entry)
+			c.mutex.Unlock()
+			return entry, true, nil
+		}
+		c.mutex.Unlock()
+
+		if entry.bodyHash != bodyHash {
+			return nil, false, errIdempotencyKeyReused
+		}
+
+		<-entry.done
+		if entry.response != nil {
+			return entry, false, nil
+		}
+
+		// The earlier request failed, so this one is tried in its place.
+	}
+}
+
+// complete records the response to a reserved key and wakes its waiters.
+func (c *idempotencyCache) complete(entry *idempotencyEntry, response []byte) {
+	entry.response = response
+	close(entry.done)
+}
+
+// release gives up a reserved key whose request failed, so a retry is
+// processed afresh.
+func (c *idempotencyCache) release(key string, entry *idempotencyEntry) {
+	c.mutex.Lock()
+	if c.entries[key] == entry {
+		delete(c.entries, key)
+		for i, k := range c.keys {
+			if k == key {
+				c.keys = append(c.keys[:i], c.keys[i+1:]...)
+				break
+			}
+		}
+	}
+	c.mutex.Unlock()
+
+	close(entry.done)
+}
+
+func (c *idempotencyCache) add(key string, entry *idempotencyEntry) {
+	if len(c.keys) >= c.capacity {
+		for i, k := range c.keys {
+			if c.entries[k].completed() {
+				delete(c.entries, k)
+				c.keys = append(c.keys[:i], c.keys[i+1:]...)
+				break
+			}
+		}
+	}
+
+	c.keys = append(c.keys, key)
+	c.entries[key] = entry
+}
+
+func (e *idempotencyEntry) completed() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/importer"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+)
+
+const MAX_IMPORT_BYTES int64 = 256 << 20 // 256 MiB
+
+type ImportController struct {
+	receiptRepository repositories.ReceiptRepository
+}
+
+func NewImportController(rr repositories.ReceiptRepository) *ImportController {
+	newImportController := &ImportController{
+		receiptRepository: rr,
+	}
+	return newImportController
+}
+
+func (ic *ImportController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"POST /admin/import",
+		middleware.LogRoute(ic.importHandler),
+	)
+}
+
+// importHandler imports the file in the request body. The format, CSV
+// column mapping and ruleset are query parameters, the latter two as JSON,
+// so the body can be streamed.
+func (ic *ImportController) importHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	format := importer.Format(params.Get("format"))
+	if format != importer.CSV && format != importer.JSON {
+		http.Error(w, "Import error: 'format' must be 'csv' or 'json'", http.StatusBadRequest)
+		return
+	}
+
+	var mapping importer.Mapping
+	if value := params.Get("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			http.Error(w, "Import error: 'mapping' isn't a JSON object of strings", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ruleset := &entities.DefaultRuleset
+	if value := params.Get("ruleset"); value != "" {
+		parsed, err := entities.ParseRuleset([]byte(value))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Import error: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		ruleset = parsed
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_IMPORT_BYTES)
+
+	report, err := importer.NewImporter(ic.receiptRepository, ruleset).Import(r.Body, format, mapping)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, importer.ErrInvalidMapping):
+			http.Error(w, fmt.Sprintf("Import error: %s", err.Error()), http.StatusBadRequest)
+		case errors.As(err, &maxBytesError):
+			msg := fmt.Sprintf(
+				"Import file is too big; %d receipts were imported before the limit",
+				report.Imported,
+			)
+			http.Error(w, msg, http.StatusRequestEntityTooLarge)
+		default:
+			writeRepositoryError(w, err)
+		}
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
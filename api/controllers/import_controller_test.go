@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/api/stream"
+	"github.com/vimolicious/receipt-processor/data/analytics"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/importer"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+const importCSV = `id,store,purchaseDate,purchaseTime,shortDescription,price,total
+R-1,Target,2022-01-01,13:01,Mountain Dew 12PK,6.49,6.49
+R-2,Target,2022-01-01,13:01,Doritos,3.35,4.00
+`
+
+func TestImport(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	mux := http.NewServeMux()
+	NewImportController(receiptRepo).AddRouteHandlers(mux)
+
+	query := url.Values{
+		"format":  {"csv"},
+		"mapping": {`{"retailer": "store"}`},
+		"ruleset": {`{"retailerCharacterPoints": 10}`},
+	}
+
+	res := serveAdminRequest(mux, "POST", "/admin/import?"+query.Encode(), importCSV)
+	if res.Code != http.StatusOK {
+		t.Fatalf("Wrong status: '%d' expected '%d': %s", res.Code, http.StatusOK, res.Body.String())
+	}
+
+	var report importer.Report
+	if err := json.Unmarshal(res.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Imported != 1 || report.Rejected != 1 || report.Rejections[0].Id != "R-2" {
+		t.Fatalf("Wrong report: %+v", report)
+	}
+
+	cases := []struct {
+		query  string
+		status int
+	}{
+		{"format=xml", http.StatusBadRequest},
+		{"format=csv&mapping=store", http.StatusBadRequest},
+		{"format=csv&mapping=" + url.QueryEscape(`{"retailer": "shop"}`), http.StatusBadRequest},
+		{"format=csv&ruleset=" + url.QueryEscape(`{"afternoonStartHour": 30}`), http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		res := serveAdminRequest(mux, "POST", "/admin/import?"+c.query, importCSV)
+		if res.Code != c.status {
+			t.Fatalf("%s: wrong status: '%d' expected '%d'", c.query, res.Code, c.status)
+		}
+	}
+}
+
+func TestImportSkipsLiveStream(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptRepo.EnableOutbox()
+
+	hub := stream.NewHub(16)
+	aggregates := analytics.NewAggregates()
+	broker := events.NewBroker()
+	broker.Subscribe(hub.HandleEvent, events.ReceiptAdded)
+	broker.Subscribe(aggregates.HandleEvent, events.ReceiptAdded, events.ReceiptImported)
+
+	mux := http.NewServeMux()
+	NewImportController(receiptRepo).AddRouteHandlers(mux)
+
+	query := url.Values{"format": {"csv"}, "mapping": {`{"retailer": "store"}`}}
+	res := serveAdminRequest(mux, "POST", "/admin/import?"+query.Encode(), importCSV)
+	if res.Code != http.StatusOK {
+		t.Fatalf("Wrong status: '%d' expected '%d': %s", res.Code, http.StatusOK, res.Body.String())
+	}
+
+	_, live, cancel := hub.Subscribe(0)
+	defer cancel()
+
+	if err := events.NewRelay(receiptRepo, broker, 0).Drain(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(live) != 0 {
+		t.Fatalf("Imported receipts reached the stream: %+v", <-live)
+	}
+
+	groups, err := aggregates.Points(analytics.Query{GroupBy: analytics.GroupByRetailer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 || groups[0].Count != 1 {
+		t.Fatalf("Imported receipts missing from analytics: %+v", groups)
+	}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// MAX_ADMIN_BODY_BYTES limits request bodies on the admin endpoints.
+const MAX_ADMIN_BODY_BYTES int64 = 64 << 10 // 64 KiB
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	res, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(res)
+}
+
+// decodeJSONBody decodes a JSON request body of at most maxBytes into v,
+// writing a 413 or 400 response and returning false if it can't.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, maxBytes int64, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, "Request body is too big", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Request body isn't valid JSON", http.StatusBadRequest)
+		}
+		return false
+	}
+
+	return true
+}
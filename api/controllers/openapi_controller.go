@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/api/openapi"
+)
+
+type OpenAPIController struct{}
+
+func NewOpenAPIController() *OpenAPIController {
+	return &OpenAPIController{}
+}
+
+func (oc *OpenAPIController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"GET /openapi.json",
+		middleware.LogRoute(oc.getSpecHandler),
+	)
+}
+
+func (oc *OpenAPIController) getSpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec)
+}
@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/api/stream"
+	"github.com/vimolicious/receipt-processor/data/analytics"
+	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/promotions"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+	"github.com/vimolicious/receipt-processor/data/rescore"
+	"github.com/vimolicious/receipt-processor/data/retailers"
+)
+
+type specSchema struct {
+	Ref        string                 `json:"$ref"`
+	Type       string                 `json:"type"`
+	Required   []string               `json:"required"`
+	Properties map[string]*specSchema `json:"properties"`
+	Items      *specSchema            `json:"items"`
+	Pattern    string                 `json:"pattern"`
+}
+
+type specMediaType struct {
+	Schema *specSchema `json:"schema"`
+}
+
+type specResponse struct {
+	Ref     string                   `json:"$ref"`
+	Content map[string]specMediaType `json:"content"`
+}
+
+type specOperation struct {
+	RequestBody *struct {
+		Content map[string]specMediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]specResponse `json:"responses"`
+}
+
+type specDocument struct {
+	Paths      map[string]map[string]*specOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*specSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) *specDocument {
+	res := httptest.NewRecorder()
+	NewOpenAPIController().getSpecHandler(
+		res, httptest.NewRequest("GET", "/openapi.json", nil),
+	)
+
+	assertStatusCode(t, res, http.StatusOK)
+
+	var spec specDocument
+	if err := json.Unmarshal(res.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Couldn't unmarshal OpenAPI document: '%s'", err.Error())
+	}
+
+	return &spec
+}
+
+func (d *specDocument) resolve(s *specSchema) *specSchema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// validate reports the first way v violates the schema, or nil.
+func (d *specDocument) validate(s *specSchema, v any, path string) error {
+	s = d.resolve(s)
+	if s == nil {
+		return fmt.Errorf("%s: unresolved schema", path)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object", path)
+		}
+		for _, field := range s.Required {
+			if _, ok := obj[field]; !ok {
+				return fmt.Errorf("%s: missing '%s'", path, field)
+			}
+		}
+		for field, fieldSchema := range s.Properties {
+			if fv, ok := obj[field]; ok {
+				if err := d.validate(fieldSchema, fv, path+"."+field); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		for i, item := range arr {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			return fmt.Errorf("%s: '%s' doesn't match '%s'", path, str, s.Pattern)
+		}
+
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected integer", path)
+		}
+	}
+
+	return nil
+}
+
+func TestOpenAPIPatternsMatchModels(t *testing.T) {
+	spec := loadSpec(t)
+
+	for field, pattern := range models.FieldPatterns() {
+		schema := spec.Components.Schemas["Receipt"]
+		name := field
+		if strings.HasPrefix(field, "items.") {
+			schema = spec.Components.Schemas["Item"]
+			name = strings.TrimPrefix(field, "items.")
+		} else if strings.HasPrefix(field, "discounts.") {
+			schema = spec.Components.Schemas["Discount"]
+			name = strings.TrimPrefix(field, "discounts.")
+		}
+
+		property, ok := schema.Properties[name]
+		if !ok {
+			t.Fatalf("Field '%s' missing from OpenAPI schema", field)
+		}
+
+		if property.Pattern != pattern {
+			t.Fatalf(
+				"Pattern for '%s' is '%s' in OpenAPI, expected '%s'",
+				field, property.Pattern, pattern,
+			)
+		}
+	}
+}
+
+func TestOpenAPIRoutesAreRegistered(t *testing.T) {
+	spec := loadSpec(t)
+
+	mux := http.NewServeMux()
+	NewReceiptController(inmemory.NewInMemoryReceiptRepository()).AddRouteHandlers(mux)
+	NewStreamController(stream.NewHub(1)).AddRouteHandlers(mux)
+	NewOpenAPIController().AddRouteHandlers(mux)
+	NewRetailerController(
+		retailers.NewRegistry(retailers.DEFAULT_FUZZY_THRESHOLD),
+	).AddRouteHandlers(mux)
+	NewPromotionController(promotions.NewEngine()).AddRouteHandlers(mux)
+	NewSimulationController(inmemory.NewInMemoryReceiptRepository()).AddRouteHandlers(mux)
+	NewRescoreController(
+		rescore.NewManager(inmemory.NewInMemoryReceiptRepository(), t.TempDir()),
+	).AddRouteHandlers(mux)
+	NewAnalyticsController(analytics.NewAggregates()).AddRouteHandlers(mux)
+	NewExportController(inmemory.NewInMemoryReceiptRepository()).AddRouteHandlers(mux)
+	NewImportController(inmemory.NewInMemoryReceiptRepository()).AddRouteHandlers(mux)
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			method = strings.ToUpper(method)
+			req := httptest.NewRequest(method, path, nil)
+
+			_, pattern := mux.Handler(req)
+			if pattern != fmt.Sprintf("%s %s", method, path) {
+				t.Fatalf(
+					"'%s %s' is documented but matches route '%s'",
+					method, path, pattern,
+				)
+			}
+		}
+	}
+}
+
+func TestOpenAPIMatchesHandlerBehaviour(t *testing.T) {
+	spec := loadSpec(t)
+	operation := spec.Paths["/receipts/process"]["post"]
+	requestSchema := operation.RequestBody.Content["application/json"].Schema
+
+	fixtures, err := filepath.Glob(filepath.Join(fixtureDir(), "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiptController := makeReceiptController()
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+
+		tc, err := loadGoldenCase(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := fixtureBody(tc)
+
+		res := callProcessReceiptHandler(t, receiptController, body)
+
+		response, ok := operation.Responses[strconv.Itoa(res.Code)]
+		if !ok {
+			t.Fatalf("%s: status '%d' is not documented", name, res.Code)
+		}
+
+		var document any
+		schemaErr := json.Unmarshal(body, &document)
+		if schemaErr == nil {
+			schemaErr = spec.validate(requestSchema, document, "receipt")
+		}
+
+		// The schema can't express every rule (e.g. the total check), but
+		// anything it rejects must be rejected by the handler too.
+		if schemaErr != nil && res.Code == http.StatusOK {
+			t.Fatalf("%s: accepted by handler but not by schema: %s", name, schemaErr)
+		}
+
+		if res.Code == http.StatusOK {
+			var responseBody any
+			if err := json.Unmarshal(res.Body.Bytes(), &responseBody); err != nil {
+				t.Fatal(err)
+			}
+
+			responseSchema := response.Content["application/json"].Schema
+			if err := spec.validate(responseSchema, responseBody, "response"); err != nil {
+				t.Fatalf("%s: response doesn't match schema: %s", name, err)
+			}
+		}
+	}
+
+	/* Oversized bodies are documented too */
+	res := callProcessReceiptHandler(
+		t, receiptController, bytes.Repeat([]byte(" "), int(MAX_RECEIPT_BYTES)+1),
+	)
+	if _, ok := operation.Responses[strconv.Itoa(res.Code)]; !ok {
+		t.Fatalf("Status '%d' is not documented", res.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/promotions"
+)
+
+type PromotionController struct {
+	engine *promotions.Engine
+}
+
+func NewPromotionController(engine *promotions.Engine) *PromotionController {
+	newPromotionController := &PromotionController{
+		engine: engine,
+	}
+	return newPromotionController
+}
+
+func (pc *PromotionController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"GET /admin/promotions",
+		middleware.LogRoute(pc.listPromotionsHandler),
+	)
+	mux.HandleFunc(
+		"POST /admin/promotions",
+		middleware.LogRoute(pc.addPromotionHandler),
+	)
+	mux.HandleFunc(
+		"GET /admin/promotions/{id}",
+		middleware.LogRoute(pc.getPromotionHandler),
+	)
+	mux.HandleFunc(
+		"PUT /admin/promotions/{id}",
+		middleware.LogRoute(pc.updatePromotionHandler),
+	)
+	mux.HandleFunc(
+		"DELETE /admin/promotions/{id}",
+		middleware.LogRoute(pc.removePromotionHandler),
+	)
+}
+
+// writePromotionError maps an error from the promotion engine to an HTTP
+// response. Validation errors say what's wrong with the promotion.
+func writePromotionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, promotions.ErrNotFound):
+		http.Error(w, "No promotion found for that ID", http.StatusNotFound)
+
+	case errors.Is(err, promotions.ErrAlreadyExists):
+		http.Error(w, "Promotion already exists", http.StatusConflict)
+
+	case errors.Is(err, promotions.ErrInvalidPromotion):
+		http.Error(w, "Promotion error: "+err.Error(), http.StatusBadRequest)
+
+	default:
+		log.Print(err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (pc *PromotionController) listPromotionsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, pc.engine.Promotions())
+}
+
+func (pc *PromotionController) addPromotionHandler(w http.ResponseWriter, r *http.Request) {
+	var promotion promotions.Promotion
+	if !decodeJSONBody(w, r, MAX_ADMIN_BODY_BYTES, &promotion) {
+		return
+	}
+
+	if err := pc.engine.Add(promotion); err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, promotion)
+}
+
+func (pc *PromotionController) getPromotionHandler(w http.ResponseWriter, r *http.Request) {
+	promotion, err := pc.engine.Promotion(r.PathValue("id"))
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, promotion)
+}
+
+func (pc *PromotionController) updatePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	var promotion promotions.Promotion
+	if !decodeJSONBody(w, r, MAX_ADMIN_BODY_BYTES, &promotion) {
+		return
+	}
+
+	// The id comes from the path; a different one in the body would
+	// silently update another promotion.
+	id := r.PathValue("id")
+	if promotion.Id != "" && promotion.Id != id {
+		http.Error(w, "Promotion error: id doesn't match the path", http.StatusBadRequest)
+		return
+	}
+	promotion.Id = id
+
+	if err := pc.engine.Update(promotion); err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, promotion)
+}
+
+func (pc *PromotionController) removePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := pc.engine.Remove(r.PathValue("id")); err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/data/promotions"
+)
+
+func TestPromotionAdmin(t *testing.T) {
+	mux := http.NewServeMux()
+	NewPromotionController(promotions.NewEngine()).AddRouteHandlers(mux)
+
+	const weekend = `"starts":"2024-06-15T00:00:00Z","ends":"2024-06-17T00:00:00Z"`
+
+	steps := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"POST", "/admin/promotions", `{"id":"target-2x","name":"2x at Target","retailerId":"target",` + weekend + `,"multiplier":2}`, http.StatusCreated},
+		{"POST", "/admin/promotions", `{"id":"target-2x","name":"2x at Target",` + weekend + `,"multiplier":2}`, http.StatusConflict},
+		{"POST", "/admin/promotions", `{"id":"bad","name":"Backwards","starts":"2024-06-17T00:00:00Z","ends":"2024-06-15T00:00:00Z","bonus":5}`, http.StatusBadRequest},
+		{"POST", "/admin/promotions", `{"id":"bad","name":"Bad date","starts":"this weekend","bonus":5}`, http.StatusBadRequest},
+		{"GET", "/admin/promotions/target-2x", "", http.StatusOK},
+		{"GET", "/admin/promotions/missing", "", http.StatusNotFound},
+		{"PUT", "/admin/promotions/target-2x", `{"name":"3x at Target","retailerId":"target",` + weekend + `,"multiplier":3}`, http.StatusOK},
+		{"PUT", "/admin/promotions/target-2x", `{"id":"other","name":"3x",` + weekend + `,"multiplier":3}`, http.StatusBadRequest},
+		{"PUT", "/admin/promotions/missing", `{"name":"3x",` + weekend + `,"multiplier":3}`, http.StatusNotFound},
+		{"DELETE", "/admin/promotions/missing", "", http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		res := serveAdminRequest(mux, step.method, step.path, step.body)
+		if res.Code != step.status {
+			t.Fatalf(
+				"Wrong status for %s %s: '%d' expected '%d': %s",
+				step.method, step.path, res.Code, step.status, res.Body.String(),
+			)
+		}
+	}
+
+	res := serveAdminRequest(mux, "GET", "/admin/promotions", "")
+
+	var listed []promotions.Promotion
+	if err := json.NewDecoder(res.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(listed) != 1 || listed[0].Multiplier != 3 {
+		t.Fatalf("Wrong promotions listed: %v", listed)
+	}
+
+	res = serveAdminRequest(mux, "DELETE", "/admin/promotions/target-2x", "")
+	if res.Code != http.StatusNoContent {
+		t.Fatalf("Wrong status for delete: '%d' expected '%d'", res.Code, http.StatusNoContent)
+	}
+}
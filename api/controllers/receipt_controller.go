@@ -1,17 +1,21 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/entities"
 	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/parse"
+	"github.com/vimolicious/receipt-processor/data/promotions"
 	"github.com/vimolicious/receipt-processor/data/repositories"
 	"github.com/vimolicious/receipt-processor/data/transform"
 )
@@ -20,12 +24,41 @@ const MAX_RECEIPT_BYTES int64 = 1 << 20 // 1 MiB
 
 type ReceiptController struct {
 	receiptRepository repositories.ReceiptRepository
+	idempotencyKeys   *idempotencyCache
+	promotions        *promotions.Engine
+	textParser        *parse.Parser
 }
 
-func NewReceiptController(rr repositories.ReceiptRepository) *ReceiptController {
+// ReceiptControllerOption configures optional ReceiptController behavior.
+type ReceiptControllerOption func(*ReceiptController)
+
+// WithPromotions applies the engine's promotions to receipts after they're
+// scored.
+func WithPromotions(engine *promotions.Engine) ReceiptControllerOption {
+	return func(rc *ReceiptController) {
+		rc.promotions = engine
+	}
+}
+
+// WithTextParser reads plain-text receipts with the parser's retailer
+// layouts instead of only the default layout.
+func WithTextParser(parser *parse.Parser) ReceiptControllerOption {
+	return func(rc *ReceiptController) {
+		rc.textParser = parser
+	}
+}
+
+func NewReceiptController(rr repositories.ReceiptRepository, opts ...ReceiptControllerOption) *ReceiptController {
 	newReceiptController := &ReceiptController{
 		receiptRepository: rr,
+		idempotencyKeys:   newIdempotencyCache(IDEMPOTENCY_CACHE_SIZE),
+		textParser:        parse.NewParser(),
+	}
+
+	for _, opt := range opts {
+		opt(newReceiptController)
 	}
+
 	return newReceiptController
 }
 
@@ -34,6 +67,10 @@ func (rc *ReceiptController) AddRouteHandlers(mux *http.ServeMux) {
 		"POST /receipts/process",
 		middleware.LogRoute(rc.processReceiptHandler),
 	)
+	mux.HandleFunc(
+		"POST /receipts/process/text",
+		middleware.LogRoute(rc.processTextReceiptHandler),
+	)
 	mux.HandleFunc(
 		"GET /receipts/{id}/points",
 		middleware.LogRoute(rc.getPointsHandler),
@@ -53,7 +90,7 @@ func (rc *ReceiptController) getPointsHandler(w http.ResponseWriter, r *http.Req
 
 	receipt, err := rc.receiptRepository.ReceiptById(id)
 	if err != nil {
-		http.Error(w, "No receipt found for that ID", http.StatusNotFound)
+		writeRepositoryError(w, err)
 		return
 	}
 
@@ -73,89 +110,216 @@ type processReceiptResponse struct {
 	Id string `json:"id"`
 }
 
-func (rc *ReceiptController) processReceiptHandler(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, MAX_RECEIPT_BYTES)
-
-	decoder := json.NewDecoder(r.Body)
+// processOnce runs process, which stores a receipt and returns the response
+// body or writes an error and returns false, at most once per
+// Idempotency-Key and route. Retries of a request get the original response
+// instead of storing a duplicate, waiting for it if it's still being
+// processed.
+func (rc *ReceiptController) processOnce(
+	w http.ResponseWriter, r *http.Request, body []byte, process func() (any, bool),
+) {
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		if response, ok := process(); ok {
+			writeJSON(w, http.StatusOK, response)
+		}
+		return
+	}
 
-	var receiptModel models.Receipt
+	// The same key sent to another route is a different request.
+	idempotencyKey = r.URL.Path + " " + idempotencyKey
 
-	err := decoder.Decode(&receiptModel)
+	entry, reserved, err := rc.idempotencyKeys.reserve(idempotencyKey, body)
 	if err != nil {
-		var syntaxError *json.SyntaxError
-		var unmarshalError *json.UnmarshalTypeError
-		_, receiptError := err.(models.ReceiptError)
-
-		switch {
-		case errors.As(err, &syntaxError):
-			msg := fmt.Sprintf(
-				"Request body JSON has bad syntax at position %d",
-				syntaxError.Offset,
-			)
-			http.Error(w, msg, http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 
-		case errors.Is(err, io.EOF):
-			msg := fmt.Sprintf("Request body is empty")
-			http.Error(w, msg, http.StatusBadRequest)
+	if !reserved {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(entry.response)
+		return
+	}
 
-		case errors.As(err, &unmarshalError):
-			msg := fmt.Sprintf(
-				"Request body has invalid value for '%s' field at position %d",
-				unmarshalError.Field,
-				unmarshalError.Offset,
-			)
-			http.Error(w, msg, http.StatusBadRequest)
+	// Release the key if the request fails, even by panicking, so retries
+	// waiting on it aren't stuck.
+	var res []byte
+	defer func() {
+		if res == nil {
+			rc.idempotencyKeys.release(idempotencyKey, entry)
+		}
+	}()
 
-		case err.Error() == "http: request body too large":
-			msg := "Request body is too big"
-			http.Error(w, msg, http.StatusRequestEntityTooLarge)
+	response, ok := process()
+	if !ok {
+		return
+	}
 
-		case receiptError:
-			msg := fmt.Sprintf("Receipt error: %s", err.Error())
-			http.Error(w, msg, http.StatusBadRequest)
+	res, err = json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	rc.idempotencyKeys.complete(entry, res)
 
-		default:
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(res)
+}
+
+func (rc *ReceiptController) processReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_RECEIPT_BYTES)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, "Request body is too big", http.StatusRequestEntityTooLarge)
+		} else {
 			log.Print(err.Error())
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
-
 		return
 	}
 
-	receipt, err := transform.ReceiptModelToEntity(&receiptModel)
-	if err != nil {
+	rc.processOnce(w, r, body, func() (any, bool) {
+		var receiptModel models.Receipt
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&receiptModel); err != nil {
+			writeReceiptDecodeError(w, err)
+			return nil, false
+		}
+
+		receipt, ok := rc.storeReceipt(w, &receiptModel)
+		if !ok {
+			return nil, false
+		}
+
+		return processReceiptResponse{Id: receipt.Id.String()}, true
+	})
+}
+
+func writeReceiptDecodeError(w http.ResponseWriter, err error) {
+	var syntaxError *json.SyntaxError
+	var unmarshalError *json.UnmarshalTypeError
+	_, receiptError := err.(models.ReceiptError)
+
+	switch {
+	case errors.As(err, &syntaxError):
+		msg := fmt.Sprintf(
+			"Request body JSON has bad syntax at position %d",
+			syntaxError.Offset,
+		)
+		http.Error(w, msg, http.StatusBadRequest)
+
+	case errors.Is(err, io.EOF):
+		msg := fmt.Sprintf("Request body is empty")
+		http.Error(w, msg, http.StatusBadRequest)
+
+	case errors.As(err, &unmarshalError):
+		msg := fmt.Sprintf(
+			"Request body has invalid value for '%s' field at position %d",
+			unmarshalError.Field,
+			unmarshalError.Offset,
+		)
+		http.Error(w, msg, http.StatusBadRequest)
+
+	case receiptError:
+		msg := fmt.Sprintf("Receipt error: %s", err.Error())
+		http.Error(w, msg, http.StatusBadRequest)
+
+	default:
+		log.Print(err.Error())
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
 	}
+}
 
-	var total float64
-	for _, item := range receipt.Items {
-		total += item.Price
+// storeReceipt scores a validated receipt, applies promotions and stores
+// it, writing an error response and returning false if it can't.
+func (rc *ReceiptController) storeReceipt(w http.ResponseWriter, receiptModel *models.Receipt) (*entities.Receipt, bool) {
+	receipt, err := transform.ReceiptModelToEntity(receiptModel)
+	if errors.Is(err, transform.ErrInvalidReceipt) {
+		msg := fmt.Sprintf("Receipt error: %s", err.Error())
+		http.Error(w, msg, http.StatusBadRequest)
+		return nil, false
+	} else if err != nil {
+		log.Print(err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
 	}
 
-	if math.Round(receipt.Total*100) != math.Round(total*100) {
-		log.Printf(
-			"%f != %f",
-			math.Round(receipt.Total*100),
-			math.Round(total*100),
-		)
-		msg := "Receipt error: wrong value in 'total'"
+	if err := entities.ReconcileTotals(receipt); err != nil {
+		msg := fmt.Sprintf("Receipt error: %s", err.Error())
 		http.Error(w, msg, http.StatusBadRequest)
-		return
+		return nil, false
+	}
+
+	if rc.promotions != nil {
+		rc.promotions.Apply(receipt)
 	}
 
 	err = rc.receiptRepository.AddReceipt(receipt)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+		if rc.promotions != nil {
+			rc.promotions.Release(receipt)
+		}
+		writeRepositoryError(w, err)
+		return nil, false
+	}
+
+	return receipt, true
+}
+
+type processTextReceiptResponse struct {
+	Id         string       `json:"id"`
+	Layout     string       `json:"layout"`
+	Confidence float64      `json:"confidence"`
+	Ambiguous  []parse.Line `json:"ambiguous"`
+}
+
+// processTextReceiptHandler processes the printed text of a receipt. Text
+// read with less than the minConfidence query parameter is rejected with
+// 422 and how each line was read, so it can be checked.
+func (rc *ReceiptController) processTextReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	minConfidence := 0.0
+	if value := r.URL.Query().Get("minConfidence"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			http.Error(w, "minConfidence must be a number from 0 to 1", http.StatusBadRequest)
+			return
+		}
+		minConfidence = parsed
 	}
 
-	res, err := json.Marshal(processReceiptResponse{Id: receipt.Id.String()})
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_RECEIPT_BYTES)
+
+	text, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		http.Error(w, "Request body is too big", http.StatusRequestEntityTooLarge)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/text")
-	w.Write(res)
+	rc.processOnce(w, r, text, func() (any, bool) {
+		result, err := rc.textParser.Parse(string(text))
+		if err != nil {
+			msg := fmt.Sprintf("Receipt error: %s", err.Error())
+			http.Error(w, msg, http.StatusBadRequest)
+			return nil, false
+		}
+
+		if result.Confidence < minConfidence {
+			writeJSON(w, http.StatusUnprocessableEntity, result)
+			return nil, false
+		}
+
+		receipt, ok := rc.storeReceipt(w, &result.Receipt)
+		if !ok {
+			return nil, false
+		}
+
+		return processTextReceiptResponse{
+			Id:         receipt.Id.String(),
+			Layout:     result.Layout,
+			Confidence: result.Confidence,
+			Ambiguous:  result.Ambiguous(),
+		}, true
+	})
 }
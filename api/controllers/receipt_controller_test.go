@@ -3,6 +3,7 @@ package controllers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,9 +11,16 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
 	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/promotions"
+	"github.com/vimolicious/receipt-processor/data/repositories"
 	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
 )
 
@@ -74,54 +82,6 @@ func assertStatusCode(t *testing.T, rr *httptest.ResponseRecorder, status int) {
  * Process Receipt Tests
  */
 
-func TestProcessReceiptHandler(t *testing.T) {
-	/* Good Cases */
-	passingCases := []string{"pass1", "pass2"}
-
-	receiptController := makeReceiptController()
-
-	for _, pc := range passingCases {
-		testCase, err := loadReceiptTestCase(pc)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		assertOkProcessResponse(t, receiptController, testCase)
-	}
-
-	/* Bad Cases */
-	failingCases := []string{
-		"failBadSyntax", "failMissingFields", "failMissingItemFields",
-		"failMalformattedFields", "failWrongTotal", "failInvalidValues",
-		"failPriceTooLarge",
-	}
-
-	for _, pc := range failingCases {
-		receiptBytes, err := loadTestCaseBytes(pc)
-		if err != nil {
-			t.Fatalf(
-				"Couldn't load raw test case '%s'; error: %s",
-				receiptBytes, err.Error(),
-			)
-		}
-
-		assertBadRequestProcessResponse(t, receiptController, receiptBytes)
-	}
-
-	emptyReceipt := make([]byte, 0)
-	assertBadRequestProcessResponse(t, receiptController, emptyReceipt)
-}
-
-func assertBadRequestProcessResponse(
-	t *testing.T, rc *ReceiptController, b []byte,
-) *httptest.ResponseRecorder {
-	res := callProcessReceiptHandler(t, rc, b)
-
-	assertStatusCode(t, res, http.StatusBadRequest)
-
-	return res
-}
-
 func assertOkProcessResponse(
 	t *testing.T, rc *ReceiptController, tc *receiptTestCase,
 ) *httptest.ResponseRecorder {
@@ -157,22 +117,10 @@ func callProcessReceiptHandler(
  * Get Points Tests
  */
 
+// Points for valid receipts are checked by TestGoldenReceipts.
 func TestGetPointsHandler(t *testing.T) {
-	/* Good Cases */
-	passingCases := []string{"pass1", "pass2"}
-
 	receiptController := makeReceiptController()
 
-	for _, pc := range passingCases {
-		testCase, err := loadReceiptTestCase(pc)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		assertCorrectPoints(t, receiptController, testCase)
-	}
-
-	/* Bad Cases */
 	invalidID := "invalid ID"
 	res := callGetPointsHandler(t, receiptController, invalidID)
 
@@ -231,3 +179,369 @@ func callGetPointsHandler(
 
 	return rr
 }
+
+/*
+ * Repository Error Tests
+ */
+
+type failingReceiptRepository struct {
+	err error
+}
+
+func (r failingReceiptRepository) ReceiptById(id uuid.UUID) (*entities.Receipt, error) {
+	return nil, fmt.Errorf("ReceiptById: %w", r.err)
+}
+
+func (r failingReceiptRepository) ListReceipts(
+	repositories.ReceiptFilter, uuid.UUID, int,
+) ([]*entities.Receipt, error) {
+	return nil, r.err
+}
+
+func (r failingReceiptRepository) UpdatePoints(uuid.UUID, int) (int, error) {
+	return 0, r.err
+}
+
+func (r failingReceiptRepository) AddReceipt(*entities.Receipt) error {
+	return fmt.Errorf("AddReceipt: %w", r.err)
+}
+
+func TestRepositoryErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{repositories.ErrUnavailable, http.StatusServiceUnavailable},
+		{repositories.ErrCapacity, http.StatusInsufficientStorage},
+		{repositories.ErrAlreadyExists, http.StatusConflict},
+		{errors.New("disk on fire"), http.StatusInternalServerError},
+	}
+
+	testCase, err := loadReceiptTestCase("pass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiptBytes, err := json.Marshal(testCase.Receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		receiptController := NewReceiptController(failingReceiptRepository{c.err})
+
+		res := callProcessReceiptHandler(t, receiptController, receiptBytes)
+		assertStatusCode(t, res, c.status)
+
+		if c.err == repositories.ErrAlreadyExists {
+			continue
+		}
+
+		// Storage failures must not be reported as a missing receipt.
+		res = callGetPointsHandler(t, receiptController, uuid.NewString())
+		assertStatusCode(t, res, c.status)
+	}
+}
+
+/*
+ * Promotion Tests
+ */
+
+func TestPromotionsAreApplied(t *testing.T) {
+	engine := promotions.NewEngine()
+	err := engine.Add(promotions.Promotion{
+		Id:          "doritos",
+		Name:        "+100 points for Doritos",
+		ItemPattern: "doritos",
+		Starts:      time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Ends:        time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC),
+		Bonus:       100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiptController := NewReceiptController(
+		inmemory.NewInMemoryReceiptRepository(), WithPromotions(engine),
+	)
+
+	testCase, err := loadReceiptTestCase("pass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCase.ExpectedPoints += 100
+	assertCorrectPoints(t, receiptController, testCase)
+}
+
+func TestProcessTextReceipt(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	mux := http.NewServeMux()
+	NewReceiptController(receiptRepo).AddRouteHandlers(mux)
+
+	// The same receipt as pass2, printed with a date whose day and month
+	// could be swapped.
+	text := "M&M Corner Market\n03/20/2022 2:33 PM\n" +
+		"Gatorade 2.25\nGatorade 2.25\nGatorade 2.25\nGatorade 2.25\n" +
+		"TOTAL 9.00\nCASH 10.00\nCHANGE 1.00\n"
+	ambiguous := strings.Replace(text, "03/20/2022", "03/04/2022", 1)
+
+	res := serveAdminRequest(mux, "POST", "/receipts/process/text", text)
+	if res.Code != http.StatusOK {
+		t.Fatalf("Wrong status: '%d' expected '%d': %s", res.Code, http.StatusOK, res.Body.String())
+	}
+
+	spec := loadSpec(t)
+	responses := spec.Paths["/receipts/process/text"]["post"].Responses
+
+	var document any
+	if err := json.Unmarshal(res.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.validate(responses["200"].Content["application/json"].Schema, document, "response"); err != nil {
+		t.Fatalf("Response doesn't match schema: %s", err)
+	}
+
+	var processed processTextReceiptResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &processed); err != nil {
+		t.Fatal(err)
+	}
+
+	if processed.Confidence != 1 || processed.Layout != "default" || len(processed.Ambiguous) != 0 {
+		t.Fatalf("Wrong response: %+v", processed)
+	}
+
+	receipt, err := receiptRepo.ReceiptById(uuid.MustParse(processed.Id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.Points != 109 {
+		t.Fatalf("Wrong number of points: '%d' expected '%d'", receipt.Points, 109)
+	}
+
+	res = serveAdminRequest(mux, "POST", "/receipts/process/text?minConfidence=0.9", ambiguous)
+	if res.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Wrong status: '%d' expected '%d'", res.Code, http.StatusUnprocessableEntity)
+	}
+
+	if err := json.Unmarshal(res.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.validate(responses["422"].Content["application/json"].Schema, document, "result"); err != nil {
+		t.Fatalf("Rejection doesn't match schema: %s", err)
+	}
+
+	res = serveAdminRequest(mux, "POST", "/receipts/process/text?minConfidence=0.5", ambiguous)
+	if err := json.Unmarshal(res.Body.Bytes(), &processed); err != nil {
+		t.Fatal(err)
+	}
+
+	if processed.Confidence != 0.8 || len(processed.Ambiguous) != 1 || processed.Ambiguous[0].Number != 2 {
+		t.Fatalf("Wrong response: %+v", processed)
+	}
+
+	/* Retries with the same Idempotency-Key */
+	retry := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/receipts/process/text", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "text-key")
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+		return res
+	}
+
+	first := retry(ambiguous)
+	assertStatusCode(t, first, http.StatusOK)
+
+	res = retry(ambiguous)
+	assertStatusCode(t, res, http.StatusOK)
+	if res.Body.String() != first.Body.String() {
+		t.Fatalf("Wrong retried response: '%s' expected '%s'", res.Body.String(), first.Body.String())
+	}
+
+	res = retry(text)
+	assertStatusCode(t, res, http.StatusConflict)
+
+	// Keys are scoped to the route, so this is a new request.
+	testCase, err := loadReceiptTestCase("pass2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(testCase.Receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/receipts/process", bytes.NewReader(body))
+	req.Header.Set("Idempotency-Key", "text-key")
+	res = httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	assertStatusCode(t, res, http.StatusOK)
+
+	cases := []struct {
+		path, body string
+	}{
+		{"/receipts/process/text", "M&M Corner Market\nGatorade 2.25\nTOTAL 2.25"},
+		{"/receipts/process/text", "M&M Corner Market\n03/20/2022 2:33 PM\nGatorade 2.25\nTOTAL 3.00"},
+		{"/receipts/process/text?minConfidence=high", text},
+	}
+
+	for _, c := range cases {
+		if res := serveAdminRequest(mux, "POST", c.path, c.body); res.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status for %q: '%d' expected '%d'", c.body, res.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+/*
+ * Idempotency Tests
+ */
+
+// slowReceiptRepository counts stored receipts and holds each AddReceipt
+// long enough for concurrent retries to overlap it.
+type slowReceiptRepository struct {
+	repositories.ReceiptRepository
+	added atomic.Int32
+}
+
+func (r *slowReceiptRepository) AddReceipt(receipt *entities.Receipt) error {
+	r.added.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return r.ReceiptRepository.AddReceipt(receipt)
+}
+
+func TestConcurrentIdempotentRequests(t *testing.T) {
+	receiptRepo := &slowReceiptRepository{
+		ReceiptRepository: inmemory.NewInMemoryReceiptRepository(),
+	}
+	mux := http.NewServeMux()
+	NewReceiptController(receiptRepo).AddRouteHandlers(mux)
+
+	testCase, err := loadReceiptTestCase("pass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(testCase.Receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const REQUESTS = 8
+	responses := make([]*httptest.ResponseRecorder, REQUESTS)
+
+	var wg sync.WaitGroup
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/receipts/process", bytes.NewReader(body))
+			req.Header.Set("Idempotency-Key", "same-key")
+			responses[i] = httptest.NewRecorder()
+			mux.ServeHTTP(responses[i], req)
+		}()
+	}
+	wg.Wait()
+
+	if added := receiptRepo.added.Load(); added != 1 {
+		t.Fatalf("Wrong number of receipts stored: '%d' expected '%d'", added, 1)
+	}
+
+	for _, res := range responses {
+		assertStatusCode(t, res, http.StatusOK)
+		if res.Body.String() != responses[0].Body.String() {
+			t.Fatalf("Wrong response: '%s' expected '%s'", res.Body.String(), responses[0].Body.String())
+		}
+	}
+
+	/* Reusing the key for another receipt */
+	other, err := loadReceiptTestCase("pass2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherBody, err := json.Marshal(other.Receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/receipts/process", bytes.NewReader(otherBody))
+	req.Header.Set("Idempotency-Key", "same-key")
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	assertStatusCode(t, res, http.StatusConflict)
+
+	/* Failed requests don't use up the key */
+	req = httptest.NewRequest("POST", "/receipts/process", strings.NewReader("{"))
+	req.Header.Set("Idempotency-Key", "other-key")
+	res = httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	assertStatusCode(t, res, http.StatusBadRequest)
+
+	req = httptest.NewRequest("POST", "/receipts/process", bytes.NewReader(otherBody))
+	req.Header.Set("Idempotency-Key", "other-key")
+	res = httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	assertStatusCode(t, res, http.StatusOK)
+
+	if added := receiptRepo.added.Load(); added != 2 {
+		t.Fatalf("Wrong number of receipts stored: '%d' expected '%d'", added, 2)
+	}
+}
+
+func TestIdempotencyCacheKeepsPendingKeys(t *testing.T) {
+	cache := newIdempotencyCache(1)
+
+	first, _, _ := cache.reserve("first", []byte("1"))
+	second, reserved, _ := cache.reserve("second", []byte("2"))
+	if !reserved {
+		t.Fatal("Key wasn't reserved")
+	}
+
+	// Neither request has finished, so both keys are kept.
+	if len(cache.entries) != 2 {
+		t.Fatalf("Wrong number of keys: '%d' expected '%d'", len(cache.entries), 2)
+	}
+
+	cache.complete(second, []byte(`{}`))
+	cache.reserve("third", []byte("3"))
+
+	if _, ok := cache.entries["second"]; ok {
+		t.Fatal("Completed key wasn't evicted")
+	}
+
+	if cache.entries["first"] != first {
+		t.Fatal("Pending key was evicted")
+	}
+}
+
+func TestPanickingRequestReleasesKey(t *testing.T) {
+	receiptController := makeReceiptController()
+
+	req := httptest.NewRequest("POST", "/receipts/process", strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "panic-key")
+
+	func() {
+		defer func() { recover() }()
+		receiptController.processOnce(httptest.NewRecorder(), req, []byte("{}"), func() (any, bool) {
+			panic("processing failed")
+		})
+	}()
+
+	done := make(chan bool)
+	go func() {
+		_, reserved, _ := receiptController.idempotencyKeys.reserve("/receipts/process panic-key", []byte("{}"))
+		done <- reserved
+	}()
+
+	select {
+	case reserved := <-done:
+		if !reserved {
+			t.Fatal("Key wasn't released")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Retry waited on a request that panicked")
+	}
+}
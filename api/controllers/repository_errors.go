@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/vimolicious/receipt-processor/data/repositories"
+)
+
+const UNAVAILABLE_RETRY_AFTER_SECONDS = "5"
+
+// writeRepositoryError maps an error from the repository layer to an HTTP
+// response. Errors that aren't one of the repositories sentinel errors are
+// reported as internal server errors.
+func writeRepositoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "No receipt found for that ID", http.StatusNotFound)
+
+	case errors.Is(err, repositories.ErrAlreadyExists):
+		http.Error(w, "Receipt already exists", http.StatusConflict)
+
+	case errors.Is(err, repositories.ErrCapacity):
+		log.Print(err.Error())
+		http.Error(w, "Receipt storage is full", http.StatusInsufficientStorage)
+
+	case errors.Is(err, repositories.ErrUnavailable):
+		log.Print(err.Error())
+		w.Header().Set("Retry-After", UNAVAILABLE_RETRY_AFTER_SECONDS)
+		http.Error(w, "Receipt storage is unavailable", http.StatusServiceUnavailable)
+
+	default:
+		log.Print(err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
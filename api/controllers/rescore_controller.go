@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+	"github.com/vimolicious/receipt-processor/data/rescore"
+)
+
+type RescoreController struct {
+	manager *rescore.Manager
+}
+
+func NewRescoreController(m *rescore.Manager) *RescoreController {
+	newRescoreController := &RescoreController{
+		manager: m,
+	}
+	return newRescoreController
+}
+
+func (rc *RescoreController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"POST /admin/rescore",
+		middleware.LogRoute(rc.startHandler),
+	)
+	mux.HandleFunc(
+		"GET /admin/rescore",
+		middleware.LogRoute(rc.progressHandler),
+	)
+	mux.HandleFunc(
+		"DELETE /admin/rescore",
+		middleware.LogRoute(rc.cancelHandler),
+	)
+}
+
+// rescoreRequest selects the receipts to rescore and the ruleset to score
+// them with, which defaults to the current one.
+type rescoreRequest struct {
+	Ruleset json.RawMessage            `json:"ruleset"`
+	Filter  repositories.ReceiptFilter `json:"filter"`
+}
+
+func writeRescoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, rescore.ErrJobRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, rescore.ErrNoJob):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Print(err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (rc *RescoreController) startHandler(w http.ResponseWriter, r *http.Request) {
+	var req rescoreRequest
+	if !decodeJSONBody(w, r, MAX_ADMIN_BODY_BYTES, &req) {
+		return
+	}
+
+	ruleset := entities.DefaultRuleset
+	if req.Ruleset != nil {
+		parsed, err := entities.ParseRuleset(req.Ruleset)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Rescore error: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		ruleset = *parsed
+	}
+
+	progress, err := rc.manager.Start(ruleset, req.Filter)
+	if err != nil {
+		writeRescoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, progress)
+}
+
+func (rc *RescoreController) progressHandler(w http.ResponseWriter, r *http.Request) {
+	progress, err := rc.manager.Progress()
+	if err != nil {
+		writeRescoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, progress)
+}
+
+func (rc *RescoreController) cancelHandler(w http.ResponseWriter, r *http.Request) {
+	if err := rc.manager.Cancel(); err != nil {
+		writeRescoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
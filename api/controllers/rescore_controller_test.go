@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+	"github.com/vimolicious/receipt-processor/data/rescore"
+)
+
+func TestRescore(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptController := NewReceiptController(receiptRepo)
+
+	for _, name := range []string{"pass1", "pass2"} {
+		testCase, err := loadReceiptTestCase(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCorrectPoints(t, receiptController, testCase)
+	}
+
+	manager := rescore.NewManager(receiptRepo, t.TempDir())
+	mux := http.NewServeMux()
+	NewRescoreController(manager).AddRouteHandlers(mux)
+
+	if res := serveAdminRequest(mux, "GET", "/admin/rescore", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("Wrong status before any job: '%d' expected '%d'", res.Code, http.StatusNotFound)
+	}
+
+	res := serveAdminRequest(mux, "POST", "/admin/rescore", `{"ruleset": {"roundDollarPoints": 100}`)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("Wrong status for bad JSON: '%d' expected '%d'", res.Code, http.StatusBadRequest)
+	}
+
+	res = serveAdminRequest(mux, "POST", "/admin/rescore", `{"ruleset": {"afternoonStartHour": 30}}`)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("Wrong status for invalid ruleset: '%d' expected '%d'", res.Code, http.StatusBadRequest)
+	}
+
+	// Only pass2 has a round dollar total.
+	res = serveAdminRequest(mux, "POST", "/admin/rescore", `{"ruleset": {"roundDollarPoints": 100}, "filter": {}}`)
+	if res.Code != http.StatusAccepted {
+		t.Fatalf("Wrong status: '%d' expected '%d': %s", res.Code, http.StatusAccepted, res.Body.String())
+	}
+	manager.Wait()
+
+	res = serveAdminRequest(mux, "GET", "/admin/rescore", "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("Wrong status: '%d' expected '%d'", res.Code, http.StatusOK)
+	}
+
+	var progress rescore.Progress
+	if err := json.Unmarshal(res.Body.Bytes(), &progress); err != nil {
+		t.Fatal(err)
+	}
+
+	if progress.Status != rescore.StatusCompleted || progress.Processed != 2 ||
+		progress.Changed != 1 || progress.PointsDelta != 50 {
+		t.Fatalf("Wrong progress: %+v", progress)
+	}
+
+	if res := serveAdminRequest(mux, "DELETE", "/admin/rescore", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("Wrong status cancelling a finished job: '%d' expected '%d'", res.Code, http.StatusNotFound)
+	}
+}
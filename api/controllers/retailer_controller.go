@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/retailers"
+)
+
+type RetailerController struct {
+	registry *retailers.Registry
+}
+
+func NewRetailerController(registry *retailers.Registry) *RetailerController {
+	newRetailerController := &RetailerController{
+		registry: registry,
+	}
+	return newRetailerController
+}
+
+func (rc *RetailerController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"GET /admin/retailers",
+		middleware.LogRoute(rc.listRetailersHandler),
+	)
+	mux.HandleFunc(
+		"POST /admin/retailers",
+		middleware.LogRoute(rc.addRetailerHandler),
+	)
+	mux.HandleFunc(
+		"GET /admin/retailers/resolve",
+		middleware.LogRoute(rc.resolveRetailerHandler),
+	)
+	mux.HandleFunc(
+		"GET /admin/retailers/{id}",
+		middleware.LogRoute(rc.getRetailerHandler),
+	)
+	mux.HandleFunc(
+		"POST /admin/retailers/{id}/aliases",
+		middleware.LogRoute(rc.addAliasHandler),
+	)
+	mux.HandleFunc(
+		"DELETE /admin/retailers/{id}/aliases/{alias}",
+		middleware.LogRoute(rc.removeAliasHandler),
+	)
+}
+
+// writeRetailerError maps an error from the retailer registry to an HTTP
+// response.
+func writeRetailerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, retailers.ErrNotFound):
+		http.Error(w, "No retailer or alias found", http.StatusNotFound)
+
+	case errors.Is(err, retailers.ErrAlreadyExists):
+		http.Error(w, "Retailer already exists", http.StatusConflict)
+
+	case errors.Is(err, retailers.ErrAliasConflict):
+		http.Error(w, "Alias belongs to another retailer", http.StatusConflict)
+
+	case errors.Is(err, retailers.ErrInvalidRetailer):
+		http.Error(w, "Invalid retailer id, name or alias", http.StatusBadRequest)
+
+	default:
+		log.Print(err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (rc *RetailerController) listRetailersHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, rc.registry.Retailers())
+}
+
+func (rc *RetailerController) addRetailerHandler(w http.ResponseWriter, r *http.Request) {
+	var retailer retailers.Retailer
+	if !decodeJSONBody(w, r, MAX_ADMIN_BODY_BYTES, &retailer) {
+		return
+	}
+
+	if err := rc.registry.Add(retailer); err != nil {
+		writeRetailerError(w, err)
+		return
+	}
+
+	added, err := rc.registry.Retailer(retailer.Id)
+	if err != nil {
+		writeRetailerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, added)
+}
+
+func (rc *RetailerController) getRetailerHandler(w http.ResponseWriter, r *http.Request) {
+	retailer, err := rc.registry.Retailer(r.PathValue("id"))
+	if err != nil {
+		writeRetailerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, retailer)
+}
+
+type resolveRetailerResponse struct {
+	Id string `json:"id"`
+}
+
+func (rc *RetailerController) resolveRetailerHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "Missing 'name' parameter", http.StatusBadRequest)
+		return
+	}
+
+	id, ok := rc.registry.Resolve(name)
+	if !ok {
+		writeRetailerError(w, retailers.ErrNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resolveRetailerResponse{Id: id})
+}
+
+type addAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+func (rc *RetailerController) addAliasHandler(w http.ResponseWriter, r *http.Request) {
+	var req addAliasRequest
+	if !decodeJSONBody(w, r, MAX_ADMIN_BODY_BYTES, &req) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := rc.registry.AddAlias(id, req.Alias); err != nil {
+		writeRetailerError(w, err)
+		return
+	}
+
+	retailer, err := rc.registry.Retailer(id)
+	if err != nil {
+		writeRetailerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, retailer)
+}
+
+func (rc *RetailerController) removeAliasHandler(w http.ResponseWriter, r *http.Request) {
+	err := rc.registry.RemoveAlias(r.PathValue("id"), r.PathValue("alias"))
+	if err != nil {
+		writeRetailerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
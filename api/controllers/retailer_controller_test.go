@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/data/retailers"
+)
+
+func serveAdminRequest(mux *http.ServeMux, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+	return res
+}
+
+func TestRetailerAdmin(t *testing.T) {
+	mux := http.NewServeMux()
+	NewRetailerController(
+		retailers.NewRegistry(retailers.DEFAULT_FUZZY_THRESHOLD),
+	).AddRouteHandlers(mux)
+
+	steps := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"POST", "/admin/retailers", `{"id":"walgreens","name":"Walgreens"}`, http.StatusCreated},
+		{"POST", "/admin/retailers", `{"id":"walgreens","name":"Walgreens"}`, http.StatusConflict},
+		{"POST", "/admin/retailers", `{"id":"Bad Id","name":"Bad"}`, http.StatusBadRequest},
+		{"POST", "/admin/retailers", `{`, http.StatusBadRequest},
+		{"POST", "/admin/retailers", `{"id":"target","name":"Target"}`, http.StatusCreated},
+		{"POST", "/admin/retailers/walgreens/aliases", `{"alias":"Walgreens Pharmacy"}`, http.StatusOK},
+		{"POST", "/admin/retailers/target/aliases", `{"alias":"Walgreens Pharmacy"}`, http.StatusConflict},
+		{"POST", "/admin/retailers/cvs/aliases", `{"alias":"CVS Pharmacy"}`, http.StatusNotFound},
+		{"GET", "/admin/retailers/walgreens", "", http.StatusOK},
+		{"GET", "/admin/retailers/cvs", "", http.StatusNotFound},
+		{"GET", "/admin/retailers/resolve?name=" + url.QueryEscape("WALGREENS PHARMACY #12"), "", http.StatusOK},
+		{"GET", "/admin/retailers/resolve?name=CVS", "", http.StatusNotFound},
+		{"GET", "/admin/retailers/resolve", "", http.StatusBadRequest},
+		{"DELETE", "/admin/retailers/walgreens/aliases/" + url.PathEscape("Walgreens Pharmacy"), "", http.StatusNoContent},
+		{"DELETE", "/admin/retailers/walgreens/aliases/" + url.PathEscape("Walgreens Pharmacy"), "", http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		res := serveAdminRequest(mux, step.method, step.path, step.body)
+		if res.Code != step.status {
+			t.Fatalf(
+				"Wrong status for %s %s: '%d' expected '%d'",
+				step.method, step.path, res.Code, step.status,
+			)
+		}
+	}
+
+	res := serveAdminRequest(mux, "GET", "/admin/retailers", "")
+
+	var listed []retailers.Retailer
+	if err := json.NewDecoder(res.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(listed) != 2 || listed[0].Id != "target" || listed[1].Id != "walgreens" {
+		t.Fatalf("Wrong retailers listed: %v", listed)
+	}
+
+	if len(listed[1].Aliases) != 0 {
+		t.Fatalf("Removed alias still listed: %v", listed[1].Aliases)
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+	"github.com/vimolicious/receipt-processor/data/simulation"
+	"github.com/vimolicious/receipt-processor/data/transform"
+)
+
+const MAX_SIMULATION_BYTES int64 = 8 << 20 // 8 MiB
+
+// MAX_SIMULATION_RECEIPTS bounds how many stored receipts one simulation
+// re-scores.
+const MAX_SIMULATION_RECEIPTS = 10000
+
+type SimulationController struct {
+	receiptRepository repositories.ReceiptRepository
+}
+
+func NewSimulationController(rr repositories.ReceiptRepository) *SimulationController {
+	newSimulationController := &SimulationController{
+		receiptRepository: rr,
+	}
+	return newSimulationController
+}
+
+func (sc *SimulationController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"POST /admin/simulate",
+		middleware.LogRoute(sc.simulateHandler),
+	)
+}
+
+type simulationFilter struct {
+	RetailerId    string    `json:"retailerId"`
+	Retailer      string    `json:"retailer"`
+	PurchasedFrom time.Time `json:"purchasedFrom"`
+	PurchasedTo   time.Time `json:"purchasedTo"`
+	Limit         int       `json:"limit"`
+}
+
+// simulationRequest holds a candidate ruleset and either receipts to score
+// or a filter selecting stored receipts.
+type simulationRequest struct {
+	Ruleset  json.RawMessage   `json:"ruleset"`
+	Receipts []json.RawMessage `json:"receipts"`
+	Filter   *simulationFilter `json:"filter"`
+}
+
+func (sc *SimulationController) simulateHandler(w http.ResponseWriter, r *http.Request) {
+	var req simulationRequest
+	if !decodeJSONBody(w, r, MAX_SIMULATION_BYTES, &req) {
+		return
+	}
+
+	if req.Ruleset == nil {
+		http.Error(w, "Simulation error: missing 'ruleset'", http.StatusBadRequest)
+		return
+	}
+
+	if (req.Receipts == nil) == (req.Filter == nil) {
+		msg := "Simulation error: exactly one of 'receipts' and 'filter' is required"
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	candidate, err := entities.ParseRuleset(req.Ruleset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Simulation error: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var receipts []*entities.Receipt
+	if req.Filter != nil {
+		receipts, err = sc.storedReceipts(req.Filter)
+		if err != nil {
+			writeRepositoryError(w, err)
+			return
+		}
+	} else {
+		receipts = make([]*entities.Receipt, len(req.Receipts))
+		for i, raw := range req.Receipts {
+			var receiptModel models.Receipt
+			err := json.Unmarshal(raw, &receiptModel)
+			if err == nil {
+				receipts[i], err = transform.ReceiptModelToEntity(&receiptModel)
+				if err != nil && !errors.Is(err, transform.ErrInvalidReceipt) {
+					log.Print(err.Error())
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			// Totals don't have to reconcile since they don't affect the
+			// comparison.
+			if err != nil {
+				msg := fmt.Sprintf("Receipt error in receipts[%d]: %s", i, err.Error())
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, simulation.Run(&entities.DefaultRuleset, candidate, receipts))
+}
+
+func (sc *SimulationController) storedReceipts(f *simulationFilter) ([]*entities.Receipt, error) {
+	limit := MAX_SIMULATION_RECEIPTS
+	if f.Limit > 0 {
+		limit = min(f.Limit, MAX_SIMULATION_RECEIPTS)
+	}
+
+	filter := repositories.ReceiptFilter{
+		RetailerId:    f.RetailerId,
+		Retailer:      f.Retailer,
+		PurchasedFrom: f.PurchasedFrom,
+		PurchasedTo:   f.PurchasedTo,
+	}
+
+	return sc.receiptRepository.ListReceipts(filter, uuid.Nil, limit)
+}
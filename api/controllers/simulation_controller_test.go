@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+	"github.com/vimolicious/receipt-processor/data/simulation"
+)
+
+func TestSimulate(t *testing.T) {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptController := NewReceiptController(receiptRepo)
+
+	for _, name := range []string{"pass1", "pass2"} {
+		testCase, err := loadReceiptTestCase(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCorrectPoints(t, receiptController, testCase)
+	}
+
+	pass2, err := loadTestCaseBytes("pass2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pass2Case struct {
+		Receipt json.RawMessage `json:"receipt"`
+	}
+	if err := json.Unmarshal(pass2, &pass2Case); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	NewSimulationController(receiptRepo).AddRouteHandlers(mux)
+
+	// Only pass2 has a round dollar total.
+	const ruleset = `"ruleset": {"roundDollarPoints": 100}`
+
+	cases := []struct {
+		description string
+		body        string
+		count       int
+		delta       int
+	}{
+		{"all stored", `{` + ruleset + `, "filter": {}}`, 2, 50},
+		{"filtered", `{` + ruleset + `, "filter": {"retailer": "target"}}`, 1, 0},
+		{"limited", `{` + ruleset + `, "filter": {"limit": 1}}`, 1, 0},
+		{"submitted", `{` + ruleset + `, "receipts": [` + string(pass2Case.Receipt) + `]}`, 1, 50},
+	}
+
+	for _, c := range cases {
+		res := serveAdminRequest(mux, "POST", "/admin/simulate", c.body)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: wrong status: '%d' expected '%d': %s", c.description, res.Code, http.StatusOK, res.Body.String())
+		}
+
+		var result simulation.Result
+		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Count != c.count || result.TotalDelta != c.delta {
+			t.Fatalf(
+				"%s: wrong result: '%d' receipts and delta '%d', expected '%d' and '%d'",
+				c.description, result.Count, result.TotalDelta, c.count, c.delta,
+			)
+		}
+	}
+
+	invalid := []string{
+		`{"filter": {}}`,
+		`{` + ruleset + `}`,
+		`{` + ruleset + `, "filter": {}, "receipts": []}`,
+		`{"ruleset": {"itemPairPoints": -1}, "filter": {}}`,
+		`{` + ruleset + `, "receipts": [{"retailer": "Target"}]}`,
+	}
+
+	for _, body := range invalid {
+		res := serveAdminRequest(mux, "POST", "/admin/simulate", body)
+		if res.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status for %s: '%d' expected '%d'", body, res.Code, http.StatusBadRequest)
+		}
+	}
+
+	oversized := `{` + ruleset + `, "receipts": [` + strings.Repeat(" ", int(MAX_SIMULATION_BYTES)) + `]}`
+	if res := serveAdminRequest(mux, "POST", "/admin/simulate", oversized); res.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Wrong status for an oversized body: '%d' expected '%d'", res.Code, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/api/stream"
+)
+
+const STREAM_HEARTBEAT_INTERVAL = 15 * time.Second
+
+type StreamController struct {
+	hub *stream.Hub
+}
+
+func NewStreamController(hub *stream.Hub) *StreamController {
+	newStreamController := &StreamController{
+		hub: hub,
+	}
+	return newStreamController
+}
+
+func (sc *StreamController) AddRouteHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(
+		"GET /receipts/stream",
+		middleware.LogRoute(sc.streamReceiptsHandler),
+	)
+}
+
+func (sc *StreamController) streamReceiptsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastEventId uint64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventId = id
+	}
+
+	retailer := r.URL.Query().Get("retailer")
+
+	backlog, events, cancel := sc.hub.Subscribe(lastEventId)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, event := range backlog {
+		if err := writeReceiptEvent(w, event, retailer); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(STREAM_HEARTBEAT_INTERVAL)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeReceiptEvent(w, event, retailer); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeReceiptEvent(w http.ResponseWriter, event stream.Event, retailer string) error {
+	if retailer != "" && !strings.EqualFold(event.Receipt.Retailer, retailer) {
+		return nil
+	}
+
+	data, err := json.Marshal(event.Receipt)
+	if err != nil {
+		log.Print(err.Error())
+		return nil
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: receipt\ndata: %s\n\n", event.Id, data)
+	return err
+}
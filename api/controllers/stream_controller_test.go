@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/api/stream"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+func makeStreamServer(t *testing.T) *httptest.Server {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptRepo.EnableOutbox()
+	hub := stream.NewHub(16)
+
+	broker := events.NewBroker()
+	broker.Subscribe(hub.HandleEvent, events.ReceiptAdded)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go events.NewRelay(receiptRepo, broker, 10*time.Millisecond).Run(ctx)
+
+	mux := http.NewServeMux()
+	NewReceiptController(receiptRepo).AddRouteHandlers(mux)
+	NewStreamController(hub).AddRouteHandlers(mux)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func postTestCase(t *testing.T, server *httptest.Server, name string) {
+	testCase, err := loadReceiptTestCase(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(testCase.Receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Post(
+		server.URL+"/receipts/process", "application/json", bytes.NewBuffer(body),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Wrong status code: '%d' expected '%d'", res.StatusCode, http.StatusOK)
+	}
+}
+
+// readStreamEvents connects to the stream and collects n events, returning
+// their ids and decoded payloads.
+func readStreamEvents(
+	t *testing.T, server *httptest.Server, query string, lastEventId string, n int,
+) ([]string, []stream.ReceiptEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", server.URL+"/receipts/stream"+query, nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Wrong content type: '%s'", ct)
+	}
+
+	ids := make([]string, 0, n)
+	events := make([]stream.ReceiptEvent, 0, n)
+
+	scanner := bufio.NewScanner(res.Body)
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+
+		case strings.HasPrefix(line, "data: "):
+			var event stream.ReceiptEvent
+			data := strings.TrimPrefix(line, "data: ")
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event)
+		}
+	}
+
+	if len(events) < n {
+		t.Fatalf("Expected %d events, got %d: %v", n, len(events), scanner.Err())
+	}
+
+	return ids, events
+}
+
+func TestStreamReceiptsHandler(t *testing.T) {
+	server := makeStreamServer(t)
+
+	postTestCase(t, server, "pass1")
+	postTestCase(t, server, "pass2")
+	postTestCase(t, server, "pass1")
+
+	// Let the relay deliver the events to the hub's buffer.
+	time.Sleep(100 * time.Millisecond)
+
+	/* Resume from the buffer */
+	ids, events := readStreamEvents(t, server, "", "1", 2)
+
+	if ids[0] != "2" || ids[1] != "3" {
+		t.Fatalf("Wrong event ids: %v", ids)
+	}
+
+	if events[0].Retailer != "M&M Corner Market" || events[0].Points != 109 {
+		t.Fatalf("Unexpected event: %+v", events[0])
+	}
+
+	if events[0].Total != "9.00" {
+		t.Fatalf("Wrong total: '%s' expected '9.00'", events[0].Total)
+	}
+
+	/* Filter by retailer */
+	ids, events = readStreamEvents(t, server, "?retailer=target", "1", 1)
+
+	if ids[0] != "3" || events[0].Retailer != "Target" {
+		t.Fatalf("Unexpected filtered event %s: %+v", ids[0], events[0])
+	}
+
+	/* Live events */
+	done := make(chan []stream.ReceiptEvent)
+	go func() {
+		_, events := readStreamEvents(t, server, "", "", 1)
+		done <- events
+	}()
+
+	// Give the subscriber time to connect before publishing.
+	time.Sleep(100 * time.Millisecond)
+	postTestCase(t, server, "pass2")
+
+	events = <-done
+	if events[0].Retailer != "M&M Corner Market" {
+		t.Fatalf("Unexpected live event: %+v", events[0])
+	}
+
+	/* Bad Cases */
+	req := httptest.NewRequest("GET", "/receipts/stream", nil)
+	req.Header.Set("Last-Event-ID", "not a number")
+	rr := httptest.NewRecorder()
+
+	NewStreamController(stream.NewHub(1)).streamReceiptsHandler(rr, req)
+
+	assertStatusCode(t, rr, http.StatusBadRequest)
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/api/traffic"
+)
+
+const REDACTED = "[REDACTED]"
+
+type CaptureConfig struct {
+	// SampleRate is the fraction of requests captured, from 0 to 1.
+	SampleRate float64
+	// MaxBodyBytes caps how much of each request and response body is kept.
+	MaxBodyBytes int
+	// RedactFields are JSON object keys whose values are replaced in bodies.
+	RedactFields []string
+	// RedactHeaders are request headers whose values are replaced.
+	RedactHeaders []string
+}
+
+// cappedBuffer keeps the first limit bytes written to it and remembers
+// whether anything was dropped.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+type captureResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   *cappedBuffer
+}
+
+func (w *captureResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CaptureTraffic records a sample of requests and their responses to a
+// traffic log that receiptctl replay can consume.
+func CaptureTraffic(next http.Handler, writer *traffic.RotatingWriter, config CaptureConfig) http.Handler {
+	redactFields := make(map[string]bool, len(config.RedactFields))
+	for _, field := range config.RedactFields {
+		redactFields[field] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rand.Float64() >= config.SampleRate {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+
+		requestBody := &cappedBuffer{limit: config.MaxBodyBytes}
+		if r.Body != nil {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, requestBody), r.Body}
+		}
+
+		cw := &captureResponseWriter{
+			ResponseWriter: w,
+			body:           &cappedBuffer{limit: config.MaxBodyBytes},
+		}
+
+		next.ServeHTTP(cw, r)
+
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		response := redactBody(cw.body, redactFields)
+		record := traffic.Record{
+			Time:              &start,
+			Method:            r.Method,
+			Path:              r.URL.RequestURI(),
+			Headers:           redactHeaders(r.Header, config.RedactHeaders),
+			Body:              redactBody(requestBody, redactFields),
+			ExpectedStatus:    cw.status,
+			ExpectedResponse:  &response,
+			LatencyMs:         float64(time.Since(start).Microseconds()) / 1000,
+			RequestTruncated:  requestBody.truncated,
+			ResponseTruncated: cw.body.truncated,
+		}
+
+		if err := writer.Write(record); err != nil {
+			log.Printf("Couldn't capture request: %s\n", err.Error())
+		}
+	})
+}
+
+func redactHeaders(header http.Header, redact []string) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key := range header {
+		headers[key] = header.Get(key)
+	}
+
+	for _, key := range redact {
+		key = http.CanonicalHeaderKey(key)
+		if _, ok := headers[key]; ok {
+			headers[key] = REDACTED
+		}
+	}
+
+	return headers
+}
+
+// redactBody replaces the redacted fields in a JSON body. Bodies that aren't
+// valid JSON, including truncated ones, are returned as-is unless they
+// mention a redacted field, in which case they are dropped entirely.
+func redactBody(body *cappedBuffer, redactFields map[string]bool) string {
+	if len(redactFields) == 0 || body.Len() == 0 {
+		return body.String()
+	}
+
+	var document any
+	if err := json.Unmarshal(body.Bytes(), &document); err != nil {
+		for field := range redactFields {
+			if strings.Contains(body.String(), field) {
+				return REDACTED
+			}
+		}
+		return body.String()
+	}
+
+	redacted, err := json.Marshal(redactValue(document, redactFields))
+	if err != nil {
+		return REDACTED
+	}
+
+	return string(redacted)
+}
+
+func redactValue(v any, redactFields map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if redactFields[key] {
+				v[key] = REDACTED
+			} else {
+				v[key] = redactValue(value, redactFields)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value, redactFields)
+		}
+	}
+	return v
+}
@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/api/traffic"
+)
+
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+func readCapturedRecords(t *testing.T, path string) []traffic.Record {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := traffic.ReadRecords(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return records
+}
+
+func TestCaptureTraffic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	writer, err := traffic.NewRotatingWriter(path, 1<<20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	handler := CaptureTraffic(http.HandlerFunc(echoHandler), writer, CaptureConfig{
+		SampleRate:    1,
+		MaxBodyBytes:  64,
+		RedactFields:  []string{"retailer"},
+		RedactHeaders: []string{"Authorization"},
+	})
+
+	req := httptest.NewRequest(
+		"POST", "/receipts/process?debug=1",
+		strings.NewReader(`{"retailer":"Target","total":"1.00"}`),
+	)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("POST", "/receipts/process", strings.NewReader(strings.Repeat("x", 100)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// The client still receives the full response.
+	if rr.Body.Len() != 100 {
+		t.Fatalf("Wrong response length: '%d' expected '100'", rr.Body.Len())
+	}
+
+	records := readCapturedRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("Wrong number of records: '%d' expected '2'", len(records))
+	}
+
+	record := records[0]
+	if record.Method != "POST" || record.Path != "/receipts/process?debug=1" {
+		t.Fatalf("Unexpected record: %+v", record)
+	}
+
+	if record.ExpectedStatus != http.StatusCreated {
+		t.Fatalf("Wrong status: '%d' expected '%d'", record.ExpectedStatus, http.StatusCreated)
+	}
+
+	if record.Headers["Authorization"] != REDACTED {
+		t.Fatalf("Header not redacted: '%s'", record.Headers["Authorization"])
+	}
+
+	if strings.Contains(record.Body, "Target") || !strings.Contains(record.Body, "1.00") {
+		t.Fatalf("Body not redacted: '%s'", record.Body)
+	}
+
+	if strings.Contains(*record.ExpectedResponse, "Target") {
+		t.Fatalf("Response not redacted: '%s'", *record.ExpectedResponse)
+	}
+
+	if !records[1].RequestTruncated || !records[1].ResponseTruncated || len(records[1].Body) != 64 {
+		t.Fatalf("Body not truncated: %+v", records[1])
+	}
+}
+
+func TestCaptureTrafficSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	writer, err := traffic.NewRotatingWriter(path, 1<<20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	handler := CaptureTraffic(http.HandlerFunc(echoHandler), writer, CaptureConfig{
+		SampleRate:   0,
+		MaxBodyBytes: 64,
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if records := readCapturedRecords(t, path); len(records) != 0 {
+		t.Fatalf("Captured %d records with a sample rate of 0", len(records))
+	}
+}
+
+func TestRotatingWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	writer, err := traffic.NewRotatingWriter(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	for i := 0; i < 5; i++ {
+		err := writer.Write(traffic.Record{Method: "GET", Path: "/receipts/stream/" + strings.Repeat("x", 40)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if records := readCapturedRecords(t, name); len(records) != 1 {
+			t.Fatalf("'%s' has %d records, expected 1", name, len(records))
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("Expected only 2 rotated files, stat error: %v", err)
+	}
+}
+
+func TestRotatingWriterKeepsWritingWhenRotationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	writer, err := traffic.NewRotatingWriter(path, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	record := traffic.Record{Method: "GET", Path: "/receipts/stream/" + strings.Repeat("x", 40)}
+	if err := writer.Write(record); err != nil {
+		t.Fatal(err)
+	}
+
+	// A non-empty directory in the way makes the rename fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocked"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Write(record); err == nil {
+		t.Fatal("Expected an error when rotation fails")
+	}
+
+	if records := readCapturedRecords(t, path); len(records) != 2 {
+		t.Fatalf("'%s' has %d records, expected 2", path, len(records))
+	}
+
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Write(record); err != nil {
+		t.Fatalf("Rotation didn't recover: %s", err)
+	}
+
+	if records := readCapturedRecords(t, path+".1"); len(records) != 2 {
+		t.Fatalf("'%s.1' has %d records, expected 2", path, len(records))
+	}
+
+	if records := readCapturedRecords(t, path); len(records) != 1 {
+		t.Fatalf("'%s' has %d records, expected 1", path, len(records))
+	}
+}
+
+func TestRotatingWriterKeepsHistoryWhenOpenFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	writer, err := traffic.NewRotatingWriter(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	record := func(i int) traffic.Record {
+		return traffic.Record{Method: "GET", Path: fmt.Sprintf("/receipts/%d/%s", i, strings.Repeat("x", 40))}
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := writer.Write(record(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A directory where the new file goes makes opening it fail.
+	if err := os.Mkdir(path+".new", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	failures := 0
+	for i := 4; i <= 9; i++ {
+		if err := writer.Write(record(i)); err != nil {
+			failures++
+		}
+	}
+
+	if failures == 0 {
+		t.Fatal("Expected errors while the new file can't be opened")
+	}
+
+	expected := map[string][]int{path: {3, 4, 5, 6, 7, 8, 9}, path + ".1": {2}, path + ".2": {1}}
+	for name, numbers := range expected {
+		records := readCapturedRecords(t, name)
+		if len(records) != len(numbers) {
+			t.Fatalf("'%s' has %d records, expected %d", name, len(records), len(numbers))
+		}
+
+		for i, r := range records {
+			if r.Path != record(numbers[i]).Path {
+				t.Fatalf("Wrong record in '%s': '%s' expected '%s'", name, r.Path, record(numbers[i]).Path)
+			}
+		}
+	}
+
+	if err := os.Remove(path + ".new"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Write(record(10)); err != nil {
+		t.Fatalf("Rotation didn't recover: %s", err)
+	}
+
+	if records := readCapturedRecords(t, path+".1"); len(records) != 7 {
+		t.Fatalf("'%s.1' has %d records, expected 7", path, len(records))
+	}
+}
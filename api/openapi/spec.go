@@ -0,0 +1,8 @@
+package openapi
+
+import _ "embed"
+
+// Spec is the OpenAPI 3 document describing every route served by the API.
+//
+//go:embed openapi.json
+var Spec []byte
@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/events"
+)
+
+const subscriberBufferSize = 64
+
+type ReceiptEvent struct {
+	Id       string `json:"id"`
+	Retailer string `json:"retailer"`
+	Total    string `json:"total"`
+	Points   int    `json:"points"`
+}
+
+type Event struct {
+	Id      uint64
+	Receipt ReceiptEvent
+}
+
+type subscriber struct {
+	events chan Event
+}
+
+// Hub fans processed receipts out to every subscriber and keeps the most
+// recent events in a bounded buffer so clients can resume after reconnecting.
+type Hub struct {
+	buffer      []Event
+	bufferSize  int
+	lastId      uint64
+	subscribers map[*subscriber]struct{}
+	mutex       sync.Mutex
+}
+
+func NewHub(bufferSize int) *Hub {
+	hub := Hub{
+		buffer:      make([]Event, 0, bufferSize),
+		bufferSize:  bufferSize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+	return &hub
+}
+
+func (h *Hub) Publish(receipt *entities.Receipt) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lastId++
+	event := Event{
+		Id: h.lastId,
+		Receipt: ReceiptEvent{
+			Id:       receipt.Id.String(),
+			Retailer: receipt.Retailer,
+			Total:    fmt.Sprintf("%.2f", receipt.Total),
+			Points:   receipt.Points,
+		},
+	}
+
+	if h.bufferSize > 0 {
+		if len(h.buffer) == h.bufferSize {
+			copy(h.buffer, h.buffer[1:])
+			h.buffer = h.buffer[:len(h.buffer)-1]
+		}
+		h.buffer = append(h.buffer, event)
+	}
+
+	for s := range h.subscribers {
+		select {
+		case s.events <- event:
+		default:
+			// The subscriber can't keep up; drop it so the client reconnects
+			// and resumes from the buffer with Last-Event-ID.
+			delete(h.subscribers, s)
+			close(s.events)
+		}
+	}
+}
+
+// HandleEvent publishes added receipts; subscribe it to an events.Broker.
+func (h *Hub) HandleEvent(event events.Event) {
+	if event.Type == events.ReceiptAdded {
+		h.Publish(&event.Receipt)
+	}
+}
+
+// Subscribe returns the buffered events published after lastEventId along
+// with a channel of future events. The channel is closed when the returned
+// cancel function is called or when the subscriber falls too far behind.
+func (h *Hub) Subscribe(lastEventId uint64) ([]Event, <-chan Event, func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	backlog := make([]Event, 0)
+	if lastEventId > 0 {
+		for _, event := range h.buffer {
+			if event.Id > lastEventId {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	s := &subscriber{events: make(chan Event, subscriberBufferSize)}
+	h.subscribers[s] = struct{}{}
+
+	cancel := func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		if _, ok := h.subscribers[s]; ok {
+			delete(h.subscribers, s)
+			close(s.events)
+		}
+	}
+
+	return backlog, s.events, cancel
+}
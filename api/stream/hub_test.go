@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+func publishReceipts(h *Hub, n int) {
+	for i := 0; i < n; i++ {
+		h.Publish(&entities.Receipt{Id: uuid.New(), Retailer: "Target", Total: 1})
+	}
+}
+
+func TestBacklogReplay(t *testing.T) {
+	hub := NewHub(3)
+	publishReceipts(hub, 5)
+
+	backlog, _, cancel := hub.Subscribe(2)
+	defer cancel()
+
+	if len(backlog) != 3 {
+		t.Fatalf("Wrong backlog length: '%d' expected '3'", len(backlog))
+	}
+
+	for i, event := range backlog {
+		if event.Id != uint64(i+3) {
+			t.Fatalf("Wrong event id: '%d' expected '%d'", event.Id, i+3)
+		}
+	}
+
+	// Only events still in the buffer can be replayed.
+	backlog, _, cancel = hub.Subscribe(1)
+	defer cancel()
+
+	if len(backlog) != 3 || backlog[0].Id != 3 {
+		t.Fatalf("Expected the 3 buffered events, got %+v", backlog)
+	}
+
+	// Without a last event id there's nothing to resume from.
+	backlog, _, cancel = hub.Subscribe(0)
+	defer cancel()
+
+	if len(backlog) != 0 {
+		t.Fatalf("Wrong backlog length: '%d' expected '0'", len(backlog))
+	}
+}
+
+func TestSubscribersReceiveEvents(t *testing.T) {
+	hub := NewHub(0)
+
+	_, events, cancel := hub.Subscribe(0)
+	publishReceipts(hub, 2)
+
+	for i := uint64(1); i <= 2; i++ {
+		if event := <-events; event.Id != i {
+			t.Fatalf("Wrong event id: '%d' expected '%d'", event.Id, i)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("Channel still open after cancel")
+	}
+
+	// Cancelling twice is harmless.
+	cancel()
+}
+
+func TestSlowSubscribersAreDropped(t *testing.T) {
+	hub := NewHub(0)
+
+	_, slow, cancelSlow := hub.Subscribe(0)
+	defer cancelSlow()
+
+	_, fast, cancelFast := hub.Subscribe(0)
+	defer cancelFast()
+
+	// A subscriber that keeps reading is never dropped.
+	for i := 0; i < subscriberBufferSize+1; i++ {
+		publishReceipts(hub, 1)
+		if _, ok := <-fast; !ok {
+			t.Fatalf("Reading subscriber dropped after %d events", i)
+		}
+	}
+
+	// The slow subscriber's buffer filled up, so it was closed after the
+	// events it had room for.
+	for i := 0; i < subscriberBufferSize; i++ {
+		if _, ok := <-slow; !ok {
+			t.Fatalf("Channel closed after %d events, expected %d", i, subscriberBufferSize)
+		}
+	}
+
+	if _, ok := <-slow; ok {
+		t.Fatal("Slow subscriber wasn't dropped")
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if len(hub.subscribers) != 1 {
+		t.Fatalf("Wrong subscriber count: '%d' expected '1'", len(hub.subscribers))
+	}
+}
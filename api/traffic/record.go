@@ -0,0 +1,60 @@
+package traffic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const MAX_RECORD_BYTES = 16 << 20 // 16 MiB
+
+// Record is one line of a JSONL request log. Captured traffic fills in the
+// response fields, which replay then treats as the expected outcome.
+type Record struct {
+	Time             *time.Time        `json:"time,omitempty"`
+	Method           string            `json:"method"`
+	Path             string            `json:"path"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Body             string            `json:"body,omitempty"`
+	ExpectedStatus   int               `json:"expectedStatus,omitempty"`
+	ExpectedResponse *string           `json:"expectedResponse,omitempty"`
+	LatencyMs        float64           `json:"latencyMs,omitempty"`
+
+	// Captured bodies over the size cap are truncated. Replay doesn't
+	// compare outcomes that depend on a truncated body.
+	RequestTruncated  bool `json:"requestTruncated,omitempty"`
+	ResponseTruncated bool `json:"responseTruncated,omitempty"`
+}
+
+// ReadRecords parses a JSONL request log, skipping blank lines.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	records := make([]Record, 0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_RECORD_BYTES)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if record.Method == "" || record.Path == "" {
+			return nil, fmt.Errorf("line %d: missing method or path", line)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,262 @@
+package traffic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Target executes a recorded request and returns the response status and
+// body.
+type Target interface {
+	Do(method, path string, headers map[string]string, body string) (int, []byte, error)
+}
+
+// REPLAY_TIMEOUT is how long a replayed request may take unless a target
+// says otherwise, so long-lived responses such as the receipt stream can't
+// stall a replay.
+const REPLAY_TIMEOUT = 30 * time.Second
+
+// HandlerTarget replays requests in-process against an http.Handler such as
+// the application's ServeMux.
+type HandlerTarget struct {
+	Handler http.Handler
+	// Timeout defaults to REPLAY_TIMEOUT.
+	Timeout time.Duration
+}
+
+func (t HandlerTarget) Do(
+	method, path string, headers map[string]string, body string,
+) (int, []byte, error) {
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = REPLAY_TIMEOUT
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, path, strings.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rr := httptest.NewRecorder()
+	t.Handler.ServeHTTP(rr, req)
+
+	if ctx.Err() != nil {
+		return rr.Code, rr.Body.Bytes(), fmt.Errorf("no complete response within %s", timeout)
+	}
+
+	return rr.Code, rr.Body.Bytes(), nil
+}
+
+// HTTPTarget replays requests against a running server.
+type HTTPTarget struct {
+	BaseURL string
+	// Client defaults to one that times out after REPLAY_TIMEOUT.
+	Client *http.Client
+}
+
+func (t HTTPTarget) Do(
+	method, path string, headers map[string]string, body string,
+) (int, []byte, error) {
+	req, err := http.NewRequest(
+		method, strings.TrimSuffix(t.BaseURL, "/")+path, strings.NewReader(body),
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: REPLAY_TIMEOUT}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.StatusCode, resBody, nil
+}
+
+type Diff struct {
+	Index          int      `json:"index"`
+	Method         string   `json:"method"`
+	Path           string   `json:"path"`
+	ExpectedStatus int      `json:"expectedStatus,omitempty"`
+	ActualStatus   int      `json:"actualStatus"`
+	Problems       []string `json:"problems"`
+}
+
+type Report struct {
+	Total  int    `json:"total"`
+	Passed int    `json:"passed"`
+	Failed int    `json:"failed"`
+	Diffs  []Diff `json:"diffs"`
+}
+
+// Replayer sends recorded requests to a Target and compares the responses
+// with the recorded expectations. Receipt IDs returned by the target are
+// substituted for the recorded IDs in later requests, so a recorded
+// process-then-get-points sequence still lines up against a fresh server.
+type Replayer struct {
+	target       Target
+	ignoreFields map[string]bool
+}
+
+// NewReplayer creates a Replayer that ignores the given JSON fields when
+// comparing response bodies.
+func NewReplayer(target Target, ignoreFields ...string) *Replayer {
+	replayer := Replayer{
+		target:       target,
+		ignoreFields: make(map[string]bool, len(ignoreFields)),
+	}
+	for _, field := range ignoreFields {
+		replayer.ignoreFields[field] = true
+	}
+	return &replayer
+}
+
+func (rp *Replayer) Replay(records []Record) Report {
+	report := Report{Diffs: make([]Diff, 0)}
+	ids := make(map[string]string)
+
+	for i, record := range records {
+		path := substituteIds(record.Path, ids)
+		body := substituteIds(record.Body, ids)
+
+		status, resBody, err := rp.target.Do(record.Method, path, record.Headers, body)
+
+		diff := Diff{
+			Index:          i + 1,
+			Method:         record.Method,
+			Path:           record.Path,
+			ExpectedStatus: record.ExpectedStatus,
+			ActualStatus:   status,
+			Problems:       make([]string, 0),
+		}
+
+		if err != nil {
+			diff.Problems = append(diff.Problems, err.Error())
+		} else {
+			compareStatus := record.ExpectedStatus != 0 && !record.RequestTruncated
+			compareResponse := record.ExpectedResponse != nil &&
+				!record.RequestTruncated && !record.ResponseTruncated
+
+			if compareStatus && record.ExpectedStatus != status {
+				diff.Problems = append(diff.Problems, fmt.Sprintf(
+					"status %d, expected %d", status, record.ExpectedStatus,
+				))
+			}
+
+			if compareResponse {
+				expected := []byte(*record.ExpectedResponse)
+				learnIds(expected, resBody, ids)
+
+				if problem := rp.compareBodies(expected, resBody); problem != "" {
+					diff.Problems = append(diff.Problems, problem)
+				}
+			}
+		}
+
+		report.Total++
+		if len(diff.Problems) > 0 {
+			report.Failed++
+			report.Diffs = append(report.Diffs, diff)
+		} else {
+			report.Passed++
+		}
+	}
+
+	return report
+}
+
+func (rp *Replayer) compareBodies(expected, actual []byte) string {
+	var expectedJSON, actualJSON any
+	if json.Unmarshal(expected, &expectedJSON) == nil && json.Unmarshal(actual, &actualJSON) == nil {
+		expectedJSON = rp.stripIgnored(expectedJSON)
+		actualJSON = rp.stripIgnored(actualJSON)
+
+		if !reflect.DeepEqual(expectedJSON, actualJSON) {
+			return fmt.Sprintf(
+				"response %s, expected %s",
+				bytes.TrimSpace(actual), bytes.TrimSpace(expected),
+			)
+		}
+		return ""
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+		return fmt.Sprintf(
+			"response %q, expected %q",
+			bytes.TrimSpace(actual), bytes.TrimSpace(expected),
+		)
+	}
+	return ""
+}
+
+func (rp *Replayer) stripIgnored(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if rp.ignoreFields[key] {
+				delete(v, key)
+			} else {
+				v[key] = rp.stripIgnored(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = rp.stripIgnored(value)
+		}
+	}
+	return v
+}
+
+// learnIds maps the UUID in a recorded response's "id" field to the UUID the
+// target returned in the same place.
+func learnIds(expected, actual []byte, ids map[string]string) {
+	var expectedRes, actualRes struct {
+		Id string `json:"id"`
+	}
+
+	if json.Unmarshal(expected, &expectedRes) != nil || json.Unmarshal(actual, &actualRes) != nil {
+		return
+	}
+
+	if uuid.Validate(expectedRes.Id) == nil && uuid.Validate(actualRes.Id) == nil {
+		ids[expectedRes.Id] = actualRes.Id
+	}
+}
+
+func substituteIds(s string, ids map[string]string) string {
+	for recorded, actual := range ids {
+		s = strings.ReplaceAll(s, recorded, actual)
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package traffic_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/api/controllers"
+	"github.com/vimolicious/receipt-processor/api/stream"
+	"github.com/vimolicious/receipt-processor/api/traffic"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+const recordedLog = `
+{"method":"POST","path":"/receipts/process","body":"{\"retailer\":\"Target\",\"purchaseDate\":\"2022-01-01\",\"purchaseTime\":\"13:01\",\"items\":[{\"shortDescription\":\"Mountain Dew 12PK\",\"price\":\"6.49\"}],\"total\":\"6.49\"}","expectedStatus":200,"expectedResponse":"{\"id\":\"7fb1377b-b223-49d9-a31a-5a02701dd310\"}"}
+{"method":"GET","path":"/receipts/7fb1377b-b223-49d9-a31a-5a02701dd310/points","expectedStatus":200,"expectedResponse":"{\"points\":32}"}
+{"method":"GET","path":"/receipts/7fb1377b-b223-49d9-a31a-5a02701dd310/points","expectedStatus":200,"expectedResponse":"{\"points\":40}"}
+{"method":"POST","path":"/receipts/process","body":"{","expectedStatus":200}
+`
+
+func TestReplay(t *testing.T) {
+	records, err := traffic.ReadRecords(strings.NewReader(recordedLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 4 {
+		t.Fatalf("Wrong number of records: '%d' expected '4'", len(records))
+	}
+
+	mux := http.NewServeMux()
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	controllers.NewReceiptController(receiptRepo).AddRouteHandlers(mux)
+
+	report := traffic.NewReplayer(traffic.HandlerTarget{Handler: mux}, "id").Replay(records)
+
+	if report.Total != 4 || report.Passed != 2 || report.Failed != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	if report.Diffs[0].Index != 3 || !strings.Contains(report.Diffs[0].Problems[0], "40") {
+		t.Fatalf("Unexpected diff: %+v", report.Diffs[0])
+	}
+
+	if report.Diffs[1].Index != 4 || report.Diffs[1].ActualStatus != http.StatusBadRequest {
+		t.Fatalf("Unexpected diff: %+v", report.Diffs[1])
+	}
+}
+
+func TestReadRecordsRejectsIncompleteLines(t *testing.T) {
+	_, err := traffic.ReadRecords(strings.NewReader(`{"method":"GET"}`))
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("Expected an error for line 1, got: %v", err)
+	}
+}
+
+func TestReplaySurvivesBadRecords(t *testing.T) {
+	records := []traffic.Record{
+		{Method: "GET", Path: "/receipts/a b/points", ExpectedStatus: http.StatusBadRequest},
+		{Method: "GET", Path: "/receipts/%zz/points", ExpectedStatus: http.StatusOK},
+		{Method: "GE T", Path: "/receipts/process", ExpectedStatus: http.StatusOK},
+		{Method: "GET", Path: "/receipts/stream", ExpectedStatus: http.StatusOK},
+	}
+
+	mux := http.NewServeMux()
+	controllers.NewReceiptController(inmemory.NewInMemoryReceiptRepository()).AddRouteHandlers(mux)
+	controllers.NewStreamController(stream.NewHub(1)).AddRouteHandlers(mux)
+
+	target := traffic.HandlerTarget{Handler: mux, Timeout: 50 * time.Millisecond}
+	report := traffic.NewReplayer(target).Replay(records)
+
+	if report.Total != 4 || report.Passed != 1 || report.Failed != 3 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	if !strings.Contains(report.Diffs[2].Problems[0], "within") {
+		t.Fatalf("Unexpected diff for the stream: %+v", report.Diffs[2])
+	}
+}
@@ -0,0 +1,158 @@
+package traffic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingWriter appends records to a JSONL file. Once the file grows past
+// maxBytes it is renamed to "<path>.1" (shifting older files up to
+// "<path>.<maxFiles>", which is deleted) and a new file is started.
+type RotatingWriter struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	mutex    sync.Mutex
+}
+
+func NewRotatingWriter(path string, maxBytes int64, maxFiles int) (*RotatingWriter, error) {
+	rotatingWriter := RotatingWriter{
+		path:     path,
+		maxBytes: maxBytes,
+		maxFiles: maxFiles,
+	}
+
+	if err := rotatingWriter.open(); err != nil {
+		return nil, err
+	}
+
+	return &rotatingWriter, nil
+}
+
+func (w *RotatingWriter) open() error {
+	file, size, err := openAppend(w.path)
+	if err != nil {
+		return err
+	}
+
+	w.file, w.size = file, size
+
+	return nil
+}
+
+func openAppend(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, info.Size(), nil
+}
+
+// rotate starts a new file. The new file is opened before older files are
+// shifted, and the current file stays open until the new one is in place,
+// so a failed rotation leaves the writer appending where it was.
+func (w *RotatingWriter) rotate() error {
+	next := w.path + ".new"
+	file, _, err := openAppend(next)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := w.shift(); err != nil {
+		file.Close()
+		os.Remove(next)
+		return err
+	}
+
+	if err := os.Rename(next, w.path); err != nil {
+		file.Close()
+		os.Remove(next)
+		return err
+	}
+
+	previous := w.file
+	w.file, w.size = file, 0
+
+	return previous.Close()
+}
+
+// shift renames "<path>" to "<path>.1", "<path>.1" to "<path>.2" and so on,
+// replacing "<path>.<maxFiles>".
+func (w *RotatingWriter) shift() error {
+	for i := w.maxFiles; i > 0; i-- {
+		src := w.path
+		if i > 1 {
+			src = fmt.Sprintf("%s.%d", w.path, i-1)
+		}
+		dst := fmt.Sprintf("%s.%d", w.path, i)
+
+		if err := os.Rename(src, dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if w.maxFiles <= 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write appends the record. If the file is due to be rotated but can't be,
+// the record is still written and the rotation error is returned. The size
+// is then counted from zero again, so the next attempt waits for another
+// maxBytes instead of shifting older files on every write.
+func (w *RotatingWriter) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var rotateErr error
+	if w.size > 0 && w.size+int64(len(line)) > w.maxBytes {
+		if rotateErr = w.rotate(); rotateErr != nil {
+			w.size = 0
+		}
+	}
+
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if rotateErr != nil {
+		return fmt.Errorf("couldn't rotate %s: %w", w.path, rotateErr)
+	}
+
+	return nil
+}
+
+func (w *RotatingWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.file.Close()
+}
@@ -0,0 +1,338 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/export"
+	"github.com/vimolicious/receipt-processor/data/models"
+)
+
+const DEFAULT_MAX_RETRIES = 3
+const DEFAULT_RETRY_BACKOFF = 100 * time.Millisecond
+
+// MAX_RETRY_AFTER is the longest a 503 response's Retry-After is waited
+// for. Responses asking for longer are returned instead of retried.
+const MAX_RETRY_AFTER = time.Minute
+
+// Client calls the receipt processor API. Failed requests are retried on
+// network errors and 502, 503 and 504 responses, which are usually
+// transient; other errors are returned straight away. Receipts are submitted
+// with an Idempotency-Key so a retry never stores the same receipt twice.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the delay
+// before the first retry, which doubles on every attempt.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+func NewClient(baseURL string, opts ...Option) *Client {
+	newClient := &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   http.DefaultClient,
+		maxRetries:   DEFAULT_MAX_RETRIES,
+		retryBackoff: DEFAULT_RETRY_BACKOFF,
+	}
+
+	for _, opt := range opts {
+		opt(newClient)
+	}
+
+	return newClient
+}
+
+type processReceiptResponse struct {
+	Id string `json:"id"`
+}
+
+type getPointsResponse struct {
+	Points int `json:"points"`
+}
+
+// ProcessReceipt submits a receipt and returns its ID.
+func (c *Client) ProcessReceipt(ctx context.Context, receipt *models.Receipt) (string, error) {
+	body, err := json.Marshal(receipt)
+	if err != nil {
+		return "", err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Idempotency-Key", uuid.NewString())
+
+	var res processReceiptResponse
+	err = c.do(ctx, "POST", "/receipts/process", header, body, &res)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Id, nil
+}
+
+// GetPoints returns the points awarded to the receipt with the given ID.
+func (c *Client) GetPoints(ctx context.Context, id string) (int, error) {
+	path := fmt.Sprintf("/receipts/%s/points", url.PathEscape(id))
+
+	var res getPointsResponse
+	if err := c.do(ctx, "GET", path, nil, nil, &res); err != nil {
+		return 0, err
+	}
+
+	return res.Points, nil
+}
+
+// ExportFilter selects the receipts to export. Zero fields match every
+// receipt.
+type ExportFilter struct {
+	RetailerId string
+	// Retailer matches the name as written, ignoring case.
+	Retailer string
+	// PurchasedTo is exclusive.
+	PurchasedFrom time.Time
+	PurchasedTo   time.Time
+}
+
+// Export streams a CSV export of stored receipts to w. The kind is
+// "receipts" for one row per receipt or "items" for one row per item.
+func (c *Client) Export(ctx context.Context, kind string, filter ExportFilter, w io.Writer) error {
+	query := url.Values{}
+	if filter.RetailerId != "" {
+		query.Set("retailerId", filter.RetailerId)
+	}
+	if filter.Retailer != "" {
+		query.Set("retailer", filter.Retailer)
+	}
+	if !filter.PurchasedFrom.IsZero() {
+		query.Set("purchasedFrom", filter.PurchasedFrom.Format(time.RFC3339))
+	}
+	if !filter.PurchasedTo.IsZero() {
+		query.Set("purchasedTo", filter.PurchasedTo.Format(time.RFC3339))
+	}
+
+	path := fmt.Sprintf("/admin/export/%s", url.PathEscape(kind))
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	res, err := c.sendWithRetries(ctx, "GET", path, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return decodeResponse(res, nil)
+	}
+	defer res.Body.Close()
+
+	if _, err := io.Copy(w, res.Body); err != nil {
+		return err
+	}
+
+	// Trailers are only read once the body has been.
+	if message := res.Trailer.Get(export.ERROR_TRAILER); message != "" {
+		return fmt.Errorf("%w: export failed part way: %s", ErrServer, message)
+	}
+
+	return nil
+}
+
+// ImportOptions describe a file to import.
+type ImportOptions struct {
+	// Format is "csv" or "json", which includes NDJSON.
+	Format string
+	// Mapping maps receipt and item fields to CSV column names.
+	Mapping map[string]string
+	// Ruleset scores the imported receipts instead of the current rules.
+	Ruleset json.RawMessage
+}
+
+type ImportRejection struct {
+	Record int    `json:"record"`
+	Id     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+type ImportReport struct {
+	Read       int               `json:"read"`
+	Imported   int               `json:"imported"`
+	Rejected   int               `json:"rejected"`
+	Rejections []ImportRejection `json:"rejections"`
+}
+
+// Import streams a file of historical receipts to the server, which keeps
+// their original ids. Imports aren't retried, since receipts without ids
+// would be stored twice.
+func (c *Client) Import(ctx context.Context, file io.Reader, opts ImportOptions) (*ImportReport, error) {
+	query := url.Values{"format": {opts.Format}}
+	if len(opts.Mapping) > 0 {
+		mapping, err := json.Marshal(opts.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("mapping", string(mapping))
+	}
+	if len(opts.Ruleset) > 0 {
+		query.Set("ruleset", string(opts.Ruleset))
+	}
+
+	res, err := c.send(ctx, "POST", "/admin/import?"+query.Encode(), nil, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var report ImportReport
+	if err := decodeResponse(res, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+func (c *Client) do(
+	ctx context.Context, method, path string, header http.Header, body []byte, out any,
+) error {
+	res, err := c.sendWithRetries(ctx, method, path, header, body)
+	if err != nil {
+		return err
+	}
+
+	return decodeResponse(res, out)
+}
+
+// sendWithRetries returns the first response that shouldn't be retried, or
+// the last one once the retries run out.
+func (c *Client) sendWithRetries(
+	ctx context.Context, method, path string, header http.Header, body []byte,
+) (*http.Response, error) {
+	backoff := c.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		res, err := c.send(ctx, method, path, header, reader)
+
+		if !retryable(ctx, res, err) || attempt >= c.maxRetries {
+			return res, err
+		}
+
+		delay := backoff
+		if wait, ok := retryAfter(res); ok {
+			if wait > MAX_RETRY_AFTER {
+				return res, err
+			}
+			delay = wait
+		}
+
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a request that got res or err may succeed if
+// it's sent again.
+func retryable(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns how long a 503 response asks the client to wait, in
+// seconds or until a date.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil || res.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	value := res.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+func (c *Client) send(
+	ctx context.Context, method, path string, header http.Header, body io.Reader,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func decodeResponse(res *http.Response, out any) error {
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return &APIError{
+			StatusCode: res.StatusCode,
+			Message:    strings.TrimSpace(string(message)),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return errors.Join(ErrServer, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/api/controllers"
+	"github.com/vimolicious/receipt-processor/data/export"
+	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+func loadTestReceipt(t *testing.T, name string) *models.Receipt {
+	_, currentFile, _, _ := runtime.Caller(0)
+	contents, err := os.ReadFile(filepath.Join(
+		filepath.Dir(currentFile), "..", "test", "receipts", name+".json",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testCase struct {
+		Receipt models.Receipt `json:"receipt"`
+	}
+	if err := json.Unmarshal(contents, &testCase); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCase.Receipt
+}
+
+func makeServer(t *testing.T, handler func(http.Handler) http.Handler) *httptest.Server {
+	mux := http.NewServeMux()
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	controllers.NewReceiptController(receiptRepo).AddRouteHandlers(mux)
+	controllers.NewExportController(receiptRepo).AddRouteHandlers(mux)
+	controllers.NewImportController(receiptRepo).AddRouteHandlers(mux)
+
+	server := httptest.NewServer(handler(mux))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func passthrough(h http.Handler) http.Handler {
+	return h
+}
+
+func TestProcessReceiptAndGetPoints(t *testing.T) {
+	server := makeServer(t, passthrough)
+	c := NewClient(server.URL)
+	ctx := context.Background()
+
+	id, err := c.ProcessReceipt(ctx, loadTestReceipt(t, "pass2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := c.GetPoints(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if points != 109 {
+		t.Fatalf("Wrong number of points: '%d' expected '109'", points)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	server := makeServer(t, passthrough)
+	c := NewClient(server.URL)
+	ctx := context.Background()
+
+	/* 400 */
+	receipt := loadTestReceipt(t, "pass2")
+	wrongTotal := "10.00"
+	receipt.Total = &wrongTotal
+
+	_, err := c.ProcessReceipt(ctx, receipt)
+	if !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("Expected ErrBadRequest, got: %v", err)
+	}
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) || !strings.Contains(apiError.Message, "total") {
+		t.Fatalf("Expected an APIError mentioning the total, got: %v", err)
+	}
+
+	/* 404 */
+	_, err = c.GetPoints(ctx, "00000000-0000-0000-0000-000000000000")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got: %v", err)
+	}
+
+	/* 413 */
+	items := make([]models.Item, 0, 40000)
+	for len(items) < cap(items) {
+		items = append(items, (*receipt.Items)[0])
+	}
+	receipt.Items = &items
+
+	_, err = c.ProcessReceipt(ctx, receipt)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Expected ErrTooLarge, got: %v", err)
+	}
+}
+
+func TestRetriesAreIdempotent(t *testing.T) {
+	var mutex sync.Mutex
+	keys := make([]string, 0)
+
+	// Process the first attempt but lose its response, as if the connection
+	// dropped after the server stored the receipt.
+	dropFirstResponse := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			attempt := len(keys)
+			mutex.Unlock()
+
+			if attempt == 1 {
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				http.Error(w, "Bad Gateway", http.StatusBadGateway)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	server := makeServer(t, dropFirstResponse)
+	c := NewClient(server.URL, WithRetries(2, time.Millisecond))
+	ctx := context.Background()
+
+	id, err := c.ProcessReceipt(ctx, loadTestReceipt(t, "pass1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("Expected two attempts with the same key, got: %v", keys)
+	}
+
+	// A manual retry with the same key must return the same receipt.
+	body, _ := json.Marshal(loadTestReceipt(t, "pass1"))
+	req, _ := http.NewRequest("POST", server.URL+"/receipts/process", bytes.NewReader(body))
+	req.Header.Set("Idempotency-Key", keys[0])
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var processResponse processReceiptResponse
+	if err := json.NewDecoder(res.Body).Decode(&processResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	if processResponse.Id != id {
+		t.Fatalf("Wrong ID for retried request: '%s' expected '%s'", processResponse.Id, id)
+	}
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	attempts := 0
+	alwaysDown := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		})
+	}
+
+	server := makeServer(t, alwaysDown)
+	c := NewClient(server.URL, WithRetries(2, time.Millisecond))
+
+	_, err := c.GetPoints(context.Background(), "00000000-0000-0000-0000-000000000000")
+	if !errors.Is(err, ErrServer) {
+		t.Fatalf("Expected ErrServer, got: %v", err)
+	}
+
+	if attempts != 3 {
+		t.Fatalf("Wrong number of attempts: '%d' expected '3'", attempts)
+	}
+}
+
+func TestRetriesSkipPermanentErrors(t *testing.T) {
+	for _, status := range []int{
+		http.StatusInternalServerError,
+		http.StatusNotImplemented,
+		http.StatusInsufficientStorage,
+	} {
+		attempts := 0
+		failing := func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				http.Error(w, http.StatusText(status), status)
+			})
+		}
+
+		server := makeServer(t, failing)
+		c := NewClient(server.URL, WithRetries(2, time.Millisecond))
+
+		_, err := c.GetPoints(context.Background(), "00000000-0000-0000-0000-000000000000")
+		if !errors.Is(err, ErrServer) {
+			t.Fatalf("%d: expected ErrServer, got: %v", status, err)
+		}
+
+		if attempts != 1 {
+			t.Fatalf("%d: wrong number of attempts: '%d' expected '1'", status, attempts)
+		}
+	}
+}
+
+func TestRetriesHonorRetryAfter(t *testing.T) {
+	attempts := 0
+	downOnce := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	server := makeServer(t, downOnce)
+	c := NewClient(server.URL, WithRetries(2, time.Millisecond))
+
+	start := time.Now()
+	if _, err := c.ProcessReceipt(context.Background(), loadTestReceipt(t, "pass1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Retried after %s, expected at least 1s", elapsed)
+	}
+
+	/* Waits longer than MAX_RETRY_AFTER aren't retried */
+	attempts = 0
+	longOutage := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set("Retry-After", "3600")
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		})
+	}
+
+	server = makeServer(t, longOutage)
+	c = NewClient(server.URL, WithRetries(2, time.Millisecond))
+
+	_, err := c.GetPoints(context.Background(), "00000000-0000-0000-0000-000000000000")
+	if !errors.Is(err, ErrServer) || attempts != 1 {
+		t.Fatalf("Expected one attempt and ErrServer, got %d attempts and: %v", attempts, err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	server := makeServer(t, passthrough)
+	c := NewClient(server.URL)
+	ctx := context.Background()
+
+	for _, name := range []string{"pass1", "pass2"} {
+		if _, err := c.ProcessReceipt(ctx, loadTestReceipt(t, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	filter := ExportFilter{
+		Retailer:      "M&M Corner Market",
+		PurchasedFrom: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := c.Export(ctx, "items", filter, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "receiptId,") {
+		t.Fatalf("Unexpected export: %q", lines)
+	}
+
+	err := c.Export(ctx, "payments", ExportFilter{}, &b)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	server := makeServer(t, passthrough)
+	c := NewClient(server.URL)
+	ctx := context.Background()
+
+	file := "Legacy Id,Store,purchaseDate,purchaseTime,shortDescription,price,total\n" +
+		"R-1,Target,2022-01-01,13:01,Mountain Dew 12PK,6.49,6.49\n" +
+		"R-2,Target,2022-01-01,25:01,Doritos,3.35,3.35\n"
+
+	report, err := c.Import(ctx, strings.NewReader(file), ImportOptions{
+		Format:  "csv",
+		Mapping: map[string]string{"id": "Legacy Id", "retailer": "Store"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Imported != 1 || report.Rejected != 1 || report.Rejections[0].Record != 3 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	_, err = c.Import(ctx, strings.NewReader(file), ImportOptions{Format: "csv"})
+	if !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("Expected ErrBadRequest, got: %v", err)
+	}
+}
+
+func TestExportFailingPartWay(t *testing.T) {
+	failPartWay := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Trailer", export.ERROR_TRAILER)
+			w.Write([]byte("id,retailer\n"))
+			w.Header().Set(export.ERROR_TRAILER, "storage unavailable")
+		})
+	}
+
+	server := makeServer(t, failPartWay)
+	c := NewClient(server.URL)
+
+	var b bytes.Buffer
+	err := c.Export(context.Background(), "receipts", ExportFilter{}, &b)
+	if !errors.Is(err, ErrServer) || !strings.Contains(err.Error(), "storage unavailable") {
+		t.Fatalf("Expected ErrServer with the trailer's message, got: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var ErrBadRequest = errors.New("bad request")
+var ErrNotFound = errors.New("not found")
+var ErrConflict = errors.New("conflict")
+var ErrTooLarge = errors.New("request too large")
+var ErrServer = errors.New("server error")
+
+// APIError is returned for any non-2xx response. Use errors.Is with the
+// sentinel errors above to check which case it is.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("receipt processor: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusRequestEntityTooLarge:
+		return ErrTooLarge
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vimolicious/receipt-processor/client"
+	"github.com/vimolicious/receipt-processor/data/export"
+)
+
+func runExport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", "http://localhost:8080", "base URL of the server to export from")
+	retailer := flags.String("retailer", "", "only export receipts from this retailer name (case-insensitive)")
+	retailerId := flags.String("retailer-id", "", "only export receipts from this canonical retailer")
+	from := flags.String("from", "", "only export receipts purchased at or after this date or RFC 3339 time")
+	to := flags.String("to", "", "only export receipts purchased before this date or RFC 3339 time")
+	output := flags.String("o", "", "file to write the CSV to instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: receiptctl export [flags] receipts|items")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Writes stored receipts as CSV, one row per receipt or one row per item.")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	kind, err := export.ParseKind(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 2
+	}
+
+	filter := client.ExportFilter{Retailer: *retailer, RetailerId: *retailerId}
+	if *from != "" {
+		if filter.PurchasedFrom, err = export.ParseTime(*from); err != nil {
+			fmt.Fprintf(stderr, "Invalid -from: %s\n", err.Error())
+			return 2
+		}
+	}
+	if *to != "" {
+		if filter.PurchasedTo, err = export.ParseTime(*to); err != nil {
+			fmt.Fprintf(stderr, "Invalid -to: %s\n", err.Error())
+			return 2
+		}
+	}
+
+	writer := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return 1
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	err = client.NewClient(*server).Export(context.Background(), string(kind), filter, writer)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		if *output != "" {
+			// Don't leave a partial export behind.
+			os.Remove(*output)
+		}
+		return 1
+	}
+
+	return 0
+}
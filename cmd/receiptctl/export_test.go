@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/api/controllers"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+func TestExport(t *testing.T) {
+	mux := http.NewServeMux()
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	controllers.NewReceiptController(receiptRepo).AddRouteHandlers(mux)
+	controllers.NewExportController(receiptRepo).AddRouteHandlers(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := http.Post(server.URL+"/receipts/process", "application/json", strings.NewReader(targetReceipt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-server", server.URL, "-from", "2022-01-01", "-to", "2022-01-02", "receipts"}
+	if code := runExport(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Wrong exit code: '%d' expected '0'; stderr '%s'", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], ",Target,,2022-01-01,13:01,UTC,USD,1,") {
+		t.Fatalf("Unexpected export: %q", lines)
+	}
+
+	if code := runExport([]string{"-server", server.URL, "payments"}, nil, &stdout, &stderr); code != 2 {
+		t.Fatalf("Wrong exit code for unknown export: '%d' expected '2'", code)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vimolicious/receipt-processor/client"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/importer"
+)
+
+type importResult struct {
+	Source string `json:"source"`
+	*client.ImportReport
+	Error string `json:"error,omitempty"`
+}
+
+func runImport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", "http://localhost:8080", "base URL of the server to import into")
+	input := flags.String("input", "", "input format: csv or json (including NDJSON); guessed from each file's extension if empty")
+	mappingFile := flags.String("mapping", "", "JSON object mapping receipt and item fields to CSV column names")
+	rulesetFile := flags.String("ruleset", "", "JSON ruleset to score the receipts with instead of the current rules")
+	format := flags.String("format", "human", "output format: human or json")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: receiptctl import [flags] file ...")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Imports historical receipts into a server and reports the rejected ones.")
+		fmt.Fprintln(stderr, "CSV files have one row per item; consecutive rows with the same id are")
+		fmt.Fprintln(stderr, "one receipt. A file of '-' is read from stdin.")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != "human" && *format != "json" {
+		fmt.Fprintf(stderr, "Unknown format '%s'\n", *format)
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var opts client.ImportOptions
+
+	if *mappingFile != "" {
+		mapping, err := importer.LoadMapping(*mappingFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return 1
+		}
+		opts.Mapping = mapping
+	}
+
+	if *rulesetFile != "" {
+		contents, err := os.ReadFile(*rulesetFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return 1
+		}
+
+		// Check the ruleset before anything is imported with it.
+		if _, err := entities.ParseRuleset(contents); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", *rulesetFile, err.Error())
+			return 1
+		}
+		opts.Ruleset = json.RawMessage(contents)
+	}
+
+	c := client.NewClient(*server)
+	failed := false
+	encoder := json.NewEncoder(stdout)
+
+	for _, source := range flags.Args() {
+		opts.Format = *input
+		if opts.Format == "" {
+			opts.Format = guessImportFormat(source)
+		}
+
+		result := importResult{Source: source}
+
+		report, err := importSource(c, source, stdin, opts)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.ImportReport = report
+
+		if err != nil || report.Rejected > 0 {
+			failed = true
+		}
+
+		if *format == "json" {
+			encoder.Encode(result)
+		} else {
+			printImportResult(stdout, result)
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func guessImportFormat(source string) string {
+	if strings.EqualFold(filepath.Ext(source), ".csv") {
+		return string(importer.CSV)
+	}
+	return string(importer.JSON)
+}
+
+func importSource(c *client.Client, source string, stdin io.Reader, opts client.ImportOptions) (*client.ImportReport, error) {
+	reader := stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	return c.Import(context.Background(), reader, opts)
+}
+
+func printImportResult(w io.Writer, result importResult) {
+	if result.Error != "" {
+		fmt.Fprintf(w, "%s: failed: %s\n", result.Source, result.Error)
+		return
+	}
+
+	report := result.ImportReport
+	fmt.Fprintf(
+		w, "%s: %d read, %d imported, %d rejected\n",
+		result.Source, report.Read, report.Imported, report.Rejected,
+	)
+
+	for _, rejection := range report.Rejections {
+		label := fmt.Sprintf("#%d", rejection.Record)
+		if rejection.Id != "" {
+			label += fmt.Sprintf(" (%s)", rejection.Id)
+		}
+		fmt.Fprintf(w, "  %s: %s\n", label, rejection.Reason)
+	}
+
+	if unlisted := report.Rejected - len(report.Rejections); unlisted > 0 {
+		fmt.Fprintf(w, "  ... and %d more\n", unlisted)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/api/controllers"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+func TestImport(t *testing.T) {
+	mux := http.NewServeMux()
+	controllers.NewImportController(inmemory.NewInMemoryReceiptRepository()).AddRouteHandlers(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"legacy.csv": "Store,purchaseDate,purchaseTime,shortDescription,price,total\n" +
+			"Target,2022-01-01,13:01,Mountain Dew 12PK,6.49,6.49\n",
+		"receipts.ndjson": targetReceipt + "\n" + wrongTotalReceipt + "\n",
+		"mapping.json":    `{"retailer": "Store"}`,
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{
+		"-server", server.URL, "-mapping", filepath.Join(dir, "mapping.json"), "-format", "json",
+		filepath.Join(dir, "legacy.csv"), filepath.Join(dir, "receipts.ndjson"),
+	}
+
+	code := runImport(args, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("Wrong exit code: '%d' expected '1'; stderr '%s'", code, stderr.String())
+	}
+
+	decoder := json.NewDecoder(&stdout)
+	for _, expected := range []struct{ imported, rejected int }{{1, 0}, {1, 1}} {
+		var result struct {
+			Source   string `json:"source"`
+			Imported int    `json:"imported"`
+			Rejected int    `json:"rejected"`
+			Error    string `json:"error"`
+		}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Imported != expected.imported || result.Rejected != expected.rejected {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+	}
+
+	stdout.Reset()
+	code = runImport([]string{"-server", server.URL, "-input", "json", "-"}, strings.NewReader(targetReceipt), &stdout, &stderr)
+	if code != 0 || stdout.String() != "-: 1 read, 1 imported, 0 rejected\n" {
+		t.Fatalf("Unexpected output: '%d' '%s'", code, stdout.String())
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"score":  {"Score receipts without running the server", runScore},
+	"replay": {"Replay a JSONL request log and report differences", runReplay},
+	"export": {"Export stored receipts or items from a server as CSV", runExport},
+	"import": {"Import historical receipts from CSV or JSON files into a server", runImport},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: receiptctl <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	os.Exit(cmd.run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+}
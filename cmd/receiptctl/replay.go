@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/vimolicious/receipt-processor/api/controllers"
+	"github.com/vimolicious/receipt-processor/api/stream"
+	"github.com/vimolicious/receipt-processor/api/traffic"
+	"github.com/vimolicious/receipt-processor/data/analytics"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/promotions"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+	"github.com/vimolicious/receipt-processor/data/rescore"
+	"github.com/vimolicious/receipt-processor/data/retailers"
+)
+
+// STREAM_BUFFER_SIZE matches the server's, so resumed streams replay alike.
+const STREAM_BUFFER_SIZE = 1024
+
+func runReplay(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", "", "base URL of a running server; replays in-process if empty")
+	ignore := flags.String("ignore", "id", "comma-separated JSON fields to ignore when comparing responses")
+	format := flags.String("format", "human", "output format: human or json")
+	timeout := flags.Duration("timeout", traffic.REPLAY_TIMEOUT, "how long each request may take")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: receiptctl replay [flags] [file]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Replays a JSONL request log (from a file, or stdin if no file is given)")
+		fmt.Fprintln(stderr, "and reports responses that differ from the recorded expectations.")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != "human" && *format != "json" {
+		fmt.Fprintf(stderr, "Unknown format '%s'\n", *format)
+		return 2
+	}
+
+	reader := stdin
+	if flags.NArg() > 0 && flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return 1
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	records, err := traffic.ReadRecords(reader)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+
+	var target traffic.Target
+	if *server != "" {
+		target = traffic.HTTPTarget{
+			BaseURL: *server,
+			Client:  &http.Client{Timeout: *timeout},
+		}
+	} else {
+		rescoreDir, err := os.MkdirTemp("", "receiptctl-replay-")
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return 1
+		}
+		defer os.RemoveAll(rescoreDir)
+
+		target = traffic.HandlerTarget{
+			Handler: newReplayHandler(rescoreDir),
+			Timeout: *timeout,
+		}
+	}
+
+	ignoreFields := make([]string, 0)
+	for _, field := range strings.Split(*ignore, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			ignoreFields = append(ignoreFields, field)
+		}
+	}
+
+	report := traffic.NewReplayer(target, ignoreFields...).Replay(records)
+
+	if *format == "json" {
+		json.NewEncoder(stdout).Encode(report)
+	} else {
+		for _, diff := range report.Diffs {
+			fmt.Fprintf(stdout, "#%d %s %s\n", diff.Index, diff.Method, diff.Path)
+			for _, problem := range diff.Problems {
+				fmt.Fprintf(stdout, "  %s\n", problem)
+			}
+		}
+		fmt.Fprintf(
+			stdout, "%d requests: %d passed, %d failed\n",
+			report.Total, report.Passed, report.Failed,
+		)
+	}
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// newReplayHandler serves the same routes as the server, backed by fresh
+// in-memory state. Pending events are delivered before each request, so
+// the stream and analytics see every earlier receipt.
+func newReplayHandler(rescoreDir string) http.Handler {
+	receiptRepo := inmemory.NewInMemoryReceiptRepository()
+	receiptRepo.EnableOutbox()
+	promotionEngine := promotions.NewEngine()
+
+	eventBroker := events.NewBroker()
+	eventRelay := events.NewRelay(receiptRepo, eventBroker, 0)
+
+	receiptHub := stream.NewHub(STREAM_BUFFER_SIZE)
+	eventBroker.Subscribe(receiptHub.HandleEvent, events.ReceiptAdded)
+
+	pointsAggregates := analytics.NewAggregates()
+	eventBroker.Subscribe(
+		pointsAggregates.HandleEvent,
+		events.ReceiptAdded, events.ReceiptImported, events.ReceiptRescored,
+	)
+
+	mux := http.NewServeMux()
+	controllers.NewReceiptController(
+		receiptRepo, controllers.WithPromotions(promotionEngine),
+	).AddRouteHandlers(mux)
+	controllers.NewStreamController(receiptHub).AddRouteHandlers(mux)
+	controllers.NewOpenAPIController().AddRouteHandlers(mux)
+	controllers.NewRetailerController(
+		retailers.NewRegistry(retailers.DEFAULT_FUZZY_THRESHOLD),
+	).AddRouteHandlers(mux)
+	controllers.NewPromotionController(promotionEngine).AddRouteHandlers(mux)
+	controllers.NewSimulationController(receiptRepo).AddRouteHandlers(mux)
+	controllers.NewRescoreController(
+		rescore.NewManager(receiptRepo, rescoreDir),
+	).AddRouteHandlers(mux)
+	controllers.NewAnalyticsController(pointsAggregates).AddRouteHandlers(mux)
+	controllers.NewExportController(receiptRepo).AddRouteHandlers(mux)
+	controllers.NewImportController(receiptRepo).AddRouteHandlers(mux)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eventRelay.Drain()
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/api/traffic"
+)
+
+func TestReplayServesAllRoutes(t *testing.T) {
+	records := []traffic.Record{
+		{Method: "POST", Path: "/admin/retailers", Body: `{"id":"target","name":"Target"}`, ExpectedStatus: http.StatusCreated},
+		{Method: "GET", Path: "/admin/retailers/target", ExpectedStatus: http.StatusOK},
+		{Method: "POST", Path: "/receipts/process", Body: targetReceipt, ExpectedStatus: http.StatusOK},
+		{Method: "GET", Path: "/openapi.json", ExpectedStatus: http.StatusOK},
+		{Method: "GET", Path: "/analytics/points?groupBy=retailer", ExpectedStatus: http.StatusOK},
+		{Method: "GET", Path: "/admin/export/receipts", ExpectedStatus: http.StatusOK},
+		{Method: "GET", Path: "/receipts/a b/points", ExpectedStatus: http.StatusBadRequest},
+	}
+
+	var log bytes.Buffer
+	for _, record := range records {
+		if err := json.NewEncoder(&log).Encode(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runReplay([]string{"-format", "json"}, &log, &stdout, &stderr); code != 0 {
+		t.Fatalf("Wrong exit code: '%d' expected '0'; output '%s%s'", code, stdout.String(), stderr.String())
+	}
+
+	var report traffic.Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total != len(records) || report.Passed != len(records) {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	/* Malformed records fail instead of crashing the replay */
+	log.Reset()
+	log.WriteString(`{"method":"GET","path":"/receipts/%zz/points","expectedStatus":200}` + "\n")
+
+	stdout.Reset()
+	if code := runReplay(nil, &log, &stdout, &stderr); code != 1 {
+		t.Fatalf("Wrong exit code: '%d' expected '1'", code)
+	}
+
+	if !strings.Contains(stdout.String(), "invalid URL escape") {
+		t.Fatalf("Unexpected output: '%s'", stdout.String())
+	}
+}
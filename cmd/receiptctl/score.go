@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vimolicious/receipt-processor/data/currency"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/transform"
+)
+
+type scoreResult struct {
+	Source    string                `json:"source"`
+	Index     int                   `json:"index"`
+	Points    *int                  `json:"points,omitempty"`
+	Breakdown []entities.RulePoints `json:"breakdown,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+func runScore(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("score", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "human", "output format: human or json")
+	breakdown := flags.Bool("breakdown", false, "show the points awarded by each rule")
+	rates := flags.String("rates", "", "exchange rate table (JSON) for non-default currencies")
+	tolerance := flags.Float64("tolerance", entities.DEFAULT_TOTAL_TOLERANCE, "how far totals may be from the amount their lines add up to")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: receiptctl score [flags] [file ...]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Reads receipts as JSON or NDJSON from each file, or from stdin if no")
+		fmt.Fprintln(stderr, "files are given or a file is '-'.")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != "human" && *format != "json" {
+		fmt.Fprintf(stderr, "Unknown format '%s'\n", *format)
+		return 2
+	}
+
+	if *rates != "" {
+		rateTable, err := currency.LoadRateTable(*rates)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return 1
+		}
+		transform.SetExchangeRates(rateTable)
+	}
+
+	entities.SetTotalTolerance(*tolerance)
+
+	sources := flags.Args()
+	if len(sources) == 0 {
+		sources = []string{"-"}
+	}
+
+	failed := false
+	encoder := json.NewEncoder(stdout)
+
+	for _, source := range sources {
+		results, err := scoreSource(source, stdin, *breakdown)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", source, err.Error())
+			return 1
+		}
+
+		for _, result := range results {
+			if result.Error != "" {
+				failed = true
+			}
+
+			if *format == "json" {
+				encoder.Encode(result)
+			} else {
+				printHumanResult(stdout, result, len(results) > 1)
+			}
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func scoreSource(source string, stdin io.Reader, breakdown bool) ([]scoreResult, error) {
+	reader := stdin
+	if source == "-" {
+		source = "stdin"
+	} else {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	results := make([]scoreResult, 0, 1)
+
+	// A JSON document and NDJSON are both a sequence of JSON values.
+	decoder := json.NewDecoder(reader)
+	for index := 1; ; index++ {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		result := scoreResult{Source: source, Index: index}
+
+		if err != nil {
+			// The rest of the stream can't be recovered after a syntax error.
+			result.Error = err.Error()
+			results = append(results, result)
+			break
+		}
+
+		receipt, err := scoreReceipt(raw)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Points = &receipt.Points
+			if breakdown {
+				result.Breakdown = entities.PointsBreakdown(receipt)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func scoreReceipt(raw json.RawMessage) (*entities.Receipt, error) {
+	var receiptModel models.Receipt
+	if err := json.Unmarshal(raw, &receiptModel); err != nil {
+		return nil, err
+	}
+
+	receipt, err := transform.ReceiptModelToEntity(&receiptModel)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := entities.ReconcileTotals(receipt); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+func printHumanResult(w io.Writer, result scoreResult, showIndex bool) {
+	label := result.Source
+	if showIndex {
+		label = fmt.Sprintf("%s#%d", result.Source, result.Index)
+	}
+
+	if result.Error != "" {
+		fmt.Fprintf(w, "%s: invalid: %s\n", label, result.Error)
+		return
+	}
+
+	fmt.Fprintf(w, "%s: %d points\n", label, *result.Points)
+	for _, rp := range result.Breakdown {
+		fmt.Fprintf(w, "  %-22s %4d\n", rp.Rule, rp.Points)
+	}
+}
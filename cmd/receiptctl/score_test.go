@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const targetReceipt = `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01",` +
+	`"items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"total":"6.49"}`
+
+const wrongTotalReceipt = `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01",` +
+	`"items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"total":"7.00"}`
+
+func TestScoreNDJSON(t *testing.T) {
+	stdin := strings.NewReader(targetReceipt + "\n" + wrongTotalReceipt + "\n")
+	var stdout, stderr bytes.Buffer
+
+	code := runScore([]string{"-format", "json", "-breakdown"}, stdin, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("Wrong exit code: '%d' expected '1'; stderr '%s'", code, stderr.String())
+	}
+
+	decoder := json.NewDecoder(&stdout)
+
+	var valid scoreResult
+	if err := decoder.Decode(&valid); err != nil {
+		t.Fatal(err)
+	}
+
+	// 6 (retailer) + 6 (odd day) + 20 (unique items)
+	if valid.Points == nil || *valid.Points != 32 {
+		t.Fatalf("Unexpected result: %+v", valid)
+	}
+
+	var sum int
+	for _, rp := range valid.Breakdown {
+		sum += rp.Points
+	}
+	if sum != *valid.Points {
+		t.Fatalf("Breakdown adds up to '%d' expected '%d'", sum, *valid.Points)
+	}
+
+	var invalid scoreResult
+	if err := decoder.Decode(&invalid); err != nil {
+		t.Fatal(err)
+	}
+
+	if invalid.Index != 2 || !strings.Contains(invalid.Error, "total") {
+		t.Fatalf("Unexpected result: %+v", invalid)
+	}
+}
+
+func TestScoreHuman(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := runScore(nil, strings.NewReader(targetReceipt), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Wrong exit code: '%d' expected '0'; stderr '%s'", code, stderr.String())
+	}
+
+	if got := strings.TrimSpace(stdout.String()); got != "stdin: 32 points" {
+		t.Fatalf("Unexpected output: '%s'", got)
+	}
+}
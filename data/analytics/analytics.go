@@ -0,0 +1,205 @@
+// Package analytics keeps running totals of receipts and points so reports
+// don't have to scan every stored receipt.
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/events"
+)
+
+var ErrInvalidQuery = errors.New("invalid analytics query")
+
+type GroupBy string
+
+const (
+	GroupByRetailer  GroupBy = "retailer"
+	GroupByDay       GroupBy = "day"
+	GroupByWeek      GroupBy = "week"
+	GroupByHourOfDay GroupBy = "hourOfDay"
+)
+
+const DATE_FORMAT = "2006-01-02"
+
+// bucketKey identifies the finest grouping aggregates are kept at. Every
+// report is built by merging buckets, so their number grows with days,
+// hours and retailers rather than with receipts.
+type bucketKey struct {
+	// day and hour are in the receipt's own time zone, the same local time
+	// the rules score.
+	day      string
+	hour     int
+	retailer string
+}
+
+type bucket struct {
+	count      int
+	points     int
+	totalCents int64
+}
+
+// Aggregates maintains per-retailer, per-hour receipt counts, points and
+// totals from repository events.
+type Aggregates struct {
+	buckets map[bucketKey]*bucket
+	mutex   sync.RWMutex
+}
+
+func NewAggregates() *Aggregates {
+	aggregates := Aggregates{
+		buckets: make(map[bucketKey]*bucket),
+	}
+	return &aggregates
+}
+
+// HandleEvent updates the aggregates; subscribe it to an events.Broker.
+func (a *Aggregates) HandleEvent(event events.Event) {
+	switch event.Type {
+	case events.ReceiptAdded, events.ReceiptImported:
+		a.add(&event.Receipt, 1, event.Receipt.Points)
+	case events.ReceiptRescored:
+		a.add(&event.Receipt, 0, event.Receipt.Points-event.PreviousPoints)
+	}
+}
+
+func (a *Aggregates) add(r *entities.Receipt, count, points int) {
+	key := bucketKey{
+		day:      r.PurchaseDateTime.Format(DATE_FORMAT),
+		hour:     r.PurchaseDateTime.Hour(),
+		retailer: retailerKey(r),
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	b, ok := a.buckets[key]
+	if !ok {
+		b = &bucket{}
+		a.buckets[key] = b
+	}
+
+	b.count += count
+	b.points += points
+	b.totalCents += int64(count) * int64(math.Round(r.BaseAmount(r.Total)*100))
+}
+
+// retailerKey groups receipts by canonical retailer, falling back to the
+// name as written for retailers that aren't registered.
+func retailerKey(r *entities.Receipt) string {
+	if r.RetailerId != "" {
+		return r.RetailerId
+	}
+	return r.Retailer
+}
+
+// Query selects purchase dates and how to group them. From and To are
+// dates in DATE_FORMAT; To is exclusive and either may be empty.
+type Query struct {
+	GroupBy GroupBy
+	From    string
+	To      string
+}
+
+func (q *Query) Validate() error {
+	switch q.GroupBy {
+	case GroupByRetailer, GroupByDay, GroupByWeek, GroupByHourOfDay:
+	default:
+		return fmt.Errorf("%w: unknown groupBy '%s'", ErrInvalidQuery, q.GroupBy)
+	}
+
+	for _, date := range []string{q.From, q.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(DATE_FORMAT, date); err != nil {
+			return fmt.Errorf("%w: '%s' isn't a YYYY-MM-DD date", ErrInvalidQuery, date)
+		}
+	}
+
+	return nil
+}
+
+// Group holds the aggregates of one group. Totals are in the base currency.
+type Group struct {
+	Key           string  `json:"key"`
+	Count         int     `json:"count"`
+	Points        int     `json:"points"`
+	Total         float64 `json:"total"`
+	AveragePoints float64 `json:"averagePoints"`
+	AverageTotal  float64 `json:"averageTotal"`
+}
+
+// Points returns the aggregates of each group with purchases in the range,
+// ordered by key.
+func (a *Aggregates) Points(q Query) ([]Group, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]*bucket)
+
+	a.mutex.RLock()
+	for key, b := range a.buckets {
+		if (q.From != "" && key.day < q.From) || (q.To != "" && key.day >= q.To) {
+			continue
+		}
+
+		groupKey := key.group(q.GroupBy)
+		m, ok := merged[groupKey]
+		if !ok {
+			m = &bucket{}
+			merged[groupKey] = m
+		}
+
+		m.count += b.count
+		m.points += b.points
+		m.totalCents += b.totalCents
+	}
+	a.mutex.RUnlock()
+
+	groups := make([]Group, 0, len(merged))
+	for key, m := range merged {
+		group := Group{
+			Key:    key,
+			Count:  m.count,
+			Points: m.points,
+			Total:  float64(m.totalCents) / 100,
+		}
+
+		if m.count > 0 {
+			group.AveragePoints = float64(m.points) / float64(m.count)
+			group.AverageTotal = math.Round(float64(m.totalCents)/float64(m.count)) / 100
+		}
+
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Key < groups[j].Key
+	})
+
+	return groups, nil
+}
+
+// group returns the key of the group the bucket belongs to. Keys sort in
+// chronological order.
+func (k bucketKey) group(groupBy GroupBy) string {
+	switch groupBy {
+	case GroupByRetailer:
+		return k.retailer
+	case GroupByWeek:
+		// Weeks are named by the date of their Monday.
+		day, _ := time.Parse(DATE_FORMAT, k.day)
+		sinceMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -sinceMonday).Format(DATE_FORMAT)
+	case GroupByHourOfDay:
+		return fmt.Sprintf("%02d", k.hour)
+	}
+	return k.day
+}
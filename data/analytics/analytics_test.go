@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/events"
+)
+
+func addedEvent(retailerId, retailer string, purchased time.Time, total float64, points int) events.Event {
+	return events.Event{
+		Type: events.ReceiptAdded,
+		Receipt: entities.Receipt{
+			Id:               uuid.New(),
+			RetailerId:       retailerId,
+			Retailer:         retailer,
+			PurchaseDateTime: purchased,
+			Total:            total,
+			Points:           points,
+		},
+	}
+}
+
+func TestPoints(t *testing.T) {
+	aggregates := NewAggregates()
+
+	// 2024-06-10 is a Monday.
+	monday := time.Date(2024, 6, 10, 9, 30, 0, 0, time.UTC)
+	sunday := time.Date(2024, 6, 16, 14, 0, 0, 0, time.UTC)
+	nextMonday := time.Date(2024, 6, 17, 14, 45, 0, 0, time.UTC)
+
+	target := addedEvent("target", "TARGET #123", monday, 10.25, 30)
+	aggregates.HandleEvent(target)
+	aggregates.HandleEvent(addedEvent("target", "Target", sunday, 5.00, 70))
+	aggregates.HandleEvent(addedEvent("", "Corner Store", nextMonday, 1.10, 10))
+
+	// Rescoring changes the points but not the count.
+	rescored := target
+	rescored.Type = events.ReceiptRescored
+	rescored.PreviousPoints = 30
+	rescored.Receipt.Points = 40
+	aggregates.HandleEvent(rescored)
+
+	cases := []struct {
+		query  Query
+		groups []Group
+	}{
+		{
+			Query{GroupBy: GroupByRetailer},
+			[]Group{
+				{Key: "Corner Store", Count: 1, Points: 10, Total: 1.10, AveragePoints: 10, AverageTotal: 1.10},
+				{Key: "target", Count: 2, Points: 110, Total: 15.25, AveragePoints: 55, AverageTotal: 7.63},
+			},
+		},
+		{
+			Query{GroupBy: GroupByWeek},
+			[]Group{
+				{Key: "2024-06-10", Count: 2, Points: 110, Total: 15.25, AveragePoints: 55, AverageTotal: 7.63},
+				{Key: "2024-06-17", Count: 1, Points: 10, Total: 1.10, AveragePoints: 10, AverageTotal: 1.10},
+			},
+		},
+		{
+			Query{GroupBy: GroupByHourOfDay},
+			[]Group{
+				{Key: "09", Count: 1, Points: 40, Total: 10.25, AveragePoints: 40, AverageTotal: 10.25},
+				{Key: "14", Count: 2, Points: 80, Total: 6.10, AveragePoints: 40, AverageTotal: 3.05},
+			},
+		},
+		{
+			Query{GroupBy: GroupByDay, From: "2024-06-11", To: "2024-06-17"},
+			[]Group{
+				{Key: "2024-06-16", Count: 1, Points: 70, Total: 5.00, AveragePoints: 70, AverageTotal: 5.00},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		groups, err := aggregates.Points(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(groups) != len(c.groups) {
+			t.Fatalf("Wrong groups for %+v: %+v expected %+v", c.query, groups, c.groups)
+		}
+
+		for i := range groups {
+			if groups[i] != c.groups[i] {
+				t.Fatalf("Wrong group for %+v: %+v expected %+v", c.query, groups[i], c.groups[i])
+			}
+		}
+	}
+}
+
+func TestInvalidQuery(t *testing.T) {
+	aggregates := NewAggregates()
+
+	for _, q := range []Query{
+		{GroupBy: "month"},
+		{GroupBy: GroupByDay, From: "06/10/2024"},
+		{GroupBy: GroupByDay, To: "2024-02-30"},
+	} {
+		if _, err := aggregates.Points(q); !errors.Is(err, ErrInvalidQuery) {
+			t.Fatalf("Wrong error for %+v: '%v' expected '%v'", q, err, ErrInvalidQuery)
+		}
+	}
+}
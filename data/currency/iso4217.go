@@ -0,0 +1,63 @@
+package currency
+
+import (
+	"fmt"
+	"strings"
+)
+
+const DEFAULT_CURRENCY = "USD"
+
+var baseCurrency = DEFAULT_CURRENCY
+
+// SetBaseCurrency changes the currency receipts without one are in. It
+// should be called during startup.
+func SetBaseCurrency(code string) {
+	baseCurrency = strings.ToUpper(code)
+}
+
+// BaseCurrency is the currency receipts without one are in.
+func BaseCurrency() string {
+	return baseCurrency
+}
+
+// minorUnits is the number of decimal places used by each ISO 4217 currency
+// we accept.
+var minorUnits = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3,
+	"JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2,
+	"OMR": 3, "PHP": 2, "PLN": 2, "RON": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TND": 3, "TRY": 2, "TWD": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// MinorUnits returns the number of decimal places for an ISO 4217 currency
+// code. An empty code means the base currency.
+func MinorUnits(code string) (int, bool) {
+	if code == "" {
+		code = baseCurrency
+	}
+
+	units, ok := minorUnits[strings.ToUpper(code)]
+	return units, ok
+}
+
+// FormatAmount formats an amount with the currency's number of decimal
+// places, or two for unknown currencies.
+func FormatAmount(amount float64, code string) string {
+	decimals, ok := MinorUnits(code)
+	if !ok {
+		decimals = 2
+	}
+
+	return fmt.Sprintf("%.*f", decimals, amount)
+}
+
+// Codes returns every supported ISO 4217 currency code.
+func Codes() []string {
+	codes := make([]string, 0, len(minorUnits))
+	for code := range minorUnits {
+		codes = append(codes, code)
+	}
+	return codes
+}
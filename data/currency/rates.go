@@ -0,0 +1,130 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+var ErrNoRate = errors.New("no exchange rate")
+
+// Rate converts one unit of Currency into Rate units of the table's base
+// currency, starting on Effective.
+type Rate struct {
+	Currency  string    `json:"currency"`
+	Effective time.Time `json:"-"`
+	Rate      float64   `json:"rate"`
+}
+
+type rateFile struct {
+	Base  string `json:"base"`
+	Rates []struct {
+		Currency  string  `json:"currency"`
+		Effective string  `json:"effective"`
+		Rate      float64 `json:"rate"`
+	} `json:"rates"`
+}
+
+// RateTable holds exchange rates into a base currency, each effective from a
+// given date until the next rate for the same currency.
+type RateTable struct {
+	base  string
+	rates map[string][]Rate
+}
+
+// NewRateTable creates a table converting into base with no other rates.
+func NewRateTable(base string) *RateTable {
+	rateTable := RateTable{
+		base:  strings.ToUpper(base),
+		rates: make(map[string][]Rate),
+	}
+	return &rateTable
+}
+
+// LoadRateTable reads a table from a JSON file shaped like:
+//
+//	{"base": "USD", "rates": [{"currency": "CAD", "effective": "2024-01-01", "rate": 0.74}]}
+func LoadRateTable(path string) (*RateTable, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rateFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if _, ok := MinorUnits(file.Base); !ok || file.Base == "" {
+		return nil, fmt.Errorf("%s: %w '%s'", path, ErrUnknownCurrency, file.Base)
+	}
+
+	table := NewRateTable(file.Base)
+	for i, r := range file.Rates {
+		effective, err := time.Parse("2006-01-02", r.Effective)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rate %d: %w", path, i+1, err)
+		}
+
+		err = table.Add(Rate{Currency: r.Currency, Effective: effective, Rate: r.Rate})
+		if err != nil {
+			return nil, fmt.Errorf("%s: rate %d: %w", path, i+1, err)
+		}
+	}
+
+	return table, nil
+}
+
+func (t *RateTable) Base() string {
+	return t.base
+}
+
+func (t *RateTable) Add(r Rate) error {
+	r.Currency = strings.ToUpper(r.Currency)
+
+	if _, ok := MinorUnits(r.Currency); !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownCurrency, r.Currency)
+	}
+
+	if r.Rate <= 0 {
+		return fmt.Errorf("rate for %s must be positive", r.Currency)
+	}
+
+	rates := append(t.rates[r.Currency], r)
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Effective.Before(rates[j].Effective)
+	})
+	t.rates[r.Currency] = rates
+
+	return nil
+}
+
+// Rate returns the rate for converting code into the base currency on the
+// given date. An empty code means the base currency.
+func (t *RateTable) Rate(code string, on time.Time) (float64, error) {
+	code = strings.ToUpper(code)
+
+	if code == "" || code == t.base {
+		return 1, nil
+	}
+
+	// Compare calendar dates so a rate effective "2024-01-01" applies all
+	// day regardless of the purchase time's location.
+	day := time.Date(on.Year(), on.Month(), on.Day(), 0, 0, 0, 0, time.UTC)
+
+	rates := t.rates[code]
+	for i := len(rates) - 1; i >= 0; i-- {
+		if !rates[i].Effective.After(day) {
+			return rates[i].Rate, nil
+		}
+	}
+
+	return 0, fmt.Errorf(
+		"%w for %s on %s", ErrNoRate, code, day.Format("2006-01-02"),
+	)
+}
@@ -0,0 +1,82 @@
+package currency
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRateTable(t *testing.T) {
+	table, err := LoadRateTable(filepath.Join("..", "..", "test", "exchange_rates.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		code string
+		on   string
+		rate float64
+	}{
+		{"", "2020-01-01 00:00", 1},
+		{"USD", "2020-01-01 00:00", 1},
+		{"CAD", "2022-01-01 00:00", 0.80},
+		{"CAD", "2023-12-31 23:59", 0.80},
+		{"CAD", "2024-01-01 00:00", 0.74},
+		{"cad", "2030-06-15 12:00", 0.74},
+		{"JPY", "2022-03-20 14:33", 0.0075},
+	}
+
+	for _, c := range cases {
+		rate, err := table.Rate(c.code, date(c.on))
+		if err != nil {
+			t.Fatalf("%s on %s: %s", c.code, c.on, err)
+		}
+
+		if rate != c.rate {
+			t.Fatalf("%s on %s: rate '%f' expected '%f'", c.code, c.on, rate, c.rate)
+		}
+	}
+
+	if _, err := table.Rate("CAD", date("2021-12-31 23:59")); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Expected ErrNoRate before the first effective date, got: %v", err)
+	}
+
+	if _, err := table.Rate("GBP", date("2024-01-01 00:00")); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Expected ErrNoRate for a currency without rates, got: %v", err)
+	}
+}
+
+func TestLoadRateTableRejectsUnknownCurrencies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	contents := `{"base":"USD","rates":[{"currency":"XYZ","effective":"2024-01-01","rate":1}]}`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadRateTable(path); !errors.Is(err, ErrUnknownCurrency) {
+		t.Fatalf("Expected ErrUnknownCurrency, got: %v", err)
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	for code, expected := range map[string]int{"": 2, "USD": 2, "JPY": 0, "BHD": 3} {
+		units, ok := MinorUnits(code)
+		if !ok || units != expected {
+			t.Fatalf("MinorUnits(%q) = %d, %t; expected %d", code, units, ok, expected)
+		}
+	}
+
+	if _, ok := MinorUnits("XYZ"); ok {
+		t.Fatal("MinorUnits accepted 'XYZ'")
+	}
+}
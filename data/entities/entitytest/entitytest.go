@@ -0,0 +1,74 @@
+// Package entitytest generates random receipts for property tests.
+package entitytest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+// NameChars are the characters random retailer names and descriptions are
+// made of. They're valid under every string policy.
+const NameChars = "abcXYZ019 -&_"
+
+// Receipt is an entities.Receipt with arbitrary but well-formed values.
+// testing/quick generates it for properties that take a Receipt argument.
+type Receipt struct {
+	entities.Receipt
+}
+
+// Name returns a random name of 1 to maxLen characters from NameChars.
+func Name(rand *rand.Rand, maxLen int) string {
+	name := make([]byte, 1+rand.Intn(maxLen))
+	for i := range name {
+		name[i] = NameChars[rand.Intn(len(NameChars))]
+	}
+	return string(name)
+}
+
+func (Receipt) Generate(rand *rand.Rand, size int) reflect.Value {
+	items := make([]entities.Item, rand.Intn(size+1))
+	for i := range items {
+		items[i] = entities.Item{
+			ShortDescription: Name(rand, 30),
+			Price:            float64(rand.Intn(10000000)) / 100,
+		}
+
+		if rand.Intn(2) == 0 {
+			quantity := 1 + rand.Intn(9)
+			unitCents := rand.Intn(10000000 / quantity)
+			items[i].Quantity = quantity
+			items[i].UnitPrice = float64(unitCents) / 100
+			items[i].Price = float64(unitCents*quantity) / 100
+			items[i].SKU = fmt.Sprintf("SKU-%d", rand.Intn(1000))
+		}
+	}
+
+	receipt := entities.Receipt{
+		Items:    items,
+		Retailer: Name(rand, 40),
+		PurchaseDateTime: time.Date(
+			2000+rand.Intn(20), time.Month(1+rand.Intn(12)), 1+rand.Intn(28),
+			rand.Intn(24), rand.Intn(60), 0, 0, time.UTC,
+		),
+		Total: float64(rand.Intn(10000000)) / 100,
+	}
+
+	if rand.Intn(2) == 0 {
+		receipt.AccountId = fmt.Sprintf("account-%d", rand.Intn(1000))
+	}
+
+	if rand.Intn(2) == 0 {
+		tax := float64(rand.Intn(100000)) / 100
+		tip := float64(rand.Intn(100000)) / 100
+		receipt.Tax, receipt.Tip = &tax, &tip
+		receipt.Discounts = []entities.Discount{
+			{Description: Name(rand, 20), Amount: float64(1+rand.Intn(100000)) / 100},
+		}
+	}
+
+	return reflect.ValueOf(Receipt{receipt})
+}
@@ -1,6 +1,48 @@
 package entities
 
+import "fmt"
+
 type Item struct {
 	ShortDescription string
-	Price            float64
+	// Price is the line total, i.e. Quantity times UnitPrice.
+	Price float64
+
+	// Quantity is the number of units on the line; zero is treated as 1.
+	// UnitPrice is the price of one unit; zero means Price divided by the
+	// quantity.
+	Quantity  int
+	UnitPrice float64
+
+	// SKU and UPC identify the product, and are empty when unknown.
+	SKU string
+	UPC string
+}
+
+// units returns the number of units on the line.
+func (i *Item) units() int {
+	if i.Quantity < 1 {
+		return 1
+	}
+	return i.Quantity
+}
+
+// pricePerUnit returns the price of one unit on the line.
+func (i *Item) pricePerUnit() float64 {
+	if i.UnitPrice != 0 {
+		return i.UnitPrice
+	}
+	return i.Price / float64(i.units())
+}
+
+// productKey identifies the product on the line for the unique items rule.
+// Lines without a UPC or SKU are identified by description and price.
+func (i *Item) productKey() string {
+	switch {
+	case i.UPC != "":
+		return "upc:" + i.UPC
+	case i.SKU != "":
+		return "sku:" + i.SKU
+	default:
+		return fmt.Sprintf("description:%s:%v", i.ShortDescription, i.pricePerUnit())
+	}
 }
@@ -0,0 +1,96 @@
+package entities_test
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/entities/entitytest"
+)
+
+func TestPointsAreNonNegative(t *testing.T) {
+	property := func(r entitytest.Receipt) bool {
+		for _, rp := range entities.PointsBreakdown(&r.Receipt) {
+			if rp.Points < 0 {
+				return false
+			}
+		}
+		return entities.CountPoints(&r.Receipt) >= 0
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBreakdownSumsToPoints(t *testing.T) {
+	property := func(r entitytest.Receipt) bool {
+		var sum int
+		for _, rp := range entities.PointsBreakdown(&r.Receipt) {
+			sum += rp.Points
+		}
+		return sum == entities.CountPoints(&r.Receipt)
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLongerRetailerNeverLowersPoints(t *testing.T) {
+	property := func(r entitytest.Receipt, c byte) bool {
+		before := entities.CountPoints(&r.Receipt)
+
+		r.Retailer += string(entitytest.NameChars[int(c)%len(entitytest.NameChars)])
+
+		return entities.CountPoints(&r.Receipt) >= before
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func rulePoints(r *entities.Receipt, rule string) int {
+	for _, rp := range entities.PointsBreakdown(r) {
+		if rp.Rule == rule {
+			return rp.Points
+		}
+	}
+	return 0
+}
+
+// A line with a quantity earns the same item pair and description points as
+// listing each unit on its own line.
+func TestQuantityMatchesRepeatedLines(t *testing.T) {
+	property := func(r entitytest.Receipt, q uint8) bool {
+		if len(r.Items) == 0 {
+			return true
+		}
+
+		quantity := 1 + int(q)%10
+		unit := entities.Item{ShortDescription: r.Items[0].ShortDescription, Price: r.Items[0].Price}
+
+		grouped := r.Receipt
+		grouped.Items = append([]entities.Item{}, r.Items...)
+		grouped.Items[0] = entities.Item{
+			ShortDescription: unit.ShortDescription,
+			Price:            unit.Price * float64(quantity),
+			Quantity:         quantity,
+			UnitPrice:        unit.Price,
+		}
+
+		repeated := r.Receipt
+		repeated.Items = append([]entities.Item{}, r.Items[1:]...)
+		for i := 0; i < quantity; i++ {
+			repeated.Items = append(repeated.Items, unit)
+		}
+
+		return rulePoints(&grouped, "itemPairs") == rulePoints(&repeated, "itemPairs") &&
+			rulePoints(&grouped, "itemDescriptions") == rulePoints(&repeated, "itemDescriptions")
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
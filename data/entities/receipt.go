@@ -5,101 +5,216 @@ import (
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
 )
 
 type Receipt struct {
-	Items            []Item
-	Retailer         string
+	Items    []Item
+	Retailer string
+	// RetailerId is the canonical retailer the name was resolved to, or
+	// empty if it didn't match a known retailer. The rules score Retailer,
+	// the name as written on the receipt.
+	RetailerId       string
 	PurchaseDateTime time.Time
 	Total            float64
 	Points           int
 	Id               uuid.UUID
+
+	// Currency is the ISO 4217 code the amounts are in; empty means the base
+	// currency. ExchangeRate converts them into the base currency for
+	// scoring; zero is treated as 1.
+	Currency     string
+	ExchangeRate float64
+
+	// Subtotal, Tax, Tip and Discounts break down how the item prices add
+	// up to the total. Nil amounts mean the receipt didn't list them.
+	Subtotal  *float64
+	Tax       *float64
+	Tip       *float64
+	Discounts []Discount
+
+	// AccountId is the customer the receipt belongs to, or empty if unknown.
+	AccountId string
+	// Imported is set on historical receipts loaded in bulk rather than
+	// submitted as they happened.
+	Imported bool
+	// Promotions lists the extra points each promotion added to Points.
+	Promotions []PromotionPoints
 }
 
-func uniqueNamePoints(r *Receipt) int {
-	itemNameMap := make(map[string]map[float64]bool)
-	uniqueNames := true
-	for _, item := range r.Items {
-		priceMap, ok := itemNameMap[item.ShortDescription]
-		if ok {
-			_, ok := priceMap[item.Price]
-			if ok {
-				uniqueNames = false
-				break
-			}
-		}
+// PromotionPoints is the number of points a promotion added to a receipt
+// when it was processed, with the promotion's terms at the time so the
+// points can be worked out again if the receipt is rescored.
+type PromotionPoints struct {
+	PromotionId string  `json:"promotionId"`
+	Points      int     `json:"points"`
+	Multiplier  float64 `json:"multiplier,omitempty"`
+	Bonus       int     `json:"bonus,omitempty"`
+}
 
-		priceMap[item.Price] = true
+// PointsFor returns the points the promotion's terms add to basePoints from
+// the rules. A multiplier adds its share of the base points, so 2x adds
+// them once more.
+func (p *PromotionPoints) PointsFor(basePoints int) int {
+	points := p.Bonus
+	if p.Multiplier != 0 {
+		points += int(math.Round(float64(basePoints) * (p.Multiplier - 1)))
 	}
+	return points
+}
 
-	if uniqueNames {
-		// 20 points if all items are unique
-		return 20
+// BaseAmount converts an amount on the receipt into the base currency,
+// rounded to the cent, so the total rules see the same kind of number
+// whatever the receipt's currency.
+func (r *Receipt) BaseAmount(amount float64) float64 {
+	if r.ExchangeRate == 0 || r.ExchangeRate == 1 {
+		return amount
 	}
-	return 0
+
+	return math.Round(amount*r.ExchangeRate*100) / 100
 }
 
-func CountPoints(r *Receipt) int {
+type RulePoints struct {
+	Rule   string `json:"rule"`
+	Points int    `json:"points"`
+}
+
+type rule struct {
+	name   string
+	points func(*Ruleset, *Receipt) int
+}
+
+var rules = []rule{
+	{"retailerName", (*Ruleset).retailerNamePoints},
+	{"roundDollarTotal", (*Ruleset).roundDollarPoints},
+	{"quarterMultipleTotal", (*Ruleset).quarterMultiplePoints},
+	{"itemPairs", (*Ruleset).itemPairPoints},
+	{"itemDescriptions", (*Ruleset).itemDescriptionPoints},
+	{"oddPurchaseDay", (*Ruleset).oddDayPoints},
+	{"afternoonPurchase", (*Ruleset).afternoonPoints},
+	{"uniqueItems", (*Ruleset).uniqueNamePoints},
+}
+
+func (rs *Ruleset) retailerNamePoints(r *Receipt) int {
 	var points int
 
-	for _, c := range r.Retailer {
+	// Count characters in NFC so "é" is one letter however it was encoded.
+	for _, c := range norm.NFC.String(r.Retailer) {
 		alphanumeric := unicode.IsLetter(c) || unicode.IsDigit(c)
 		if alphanumeric {
 			// One point for every alphanumeric character in the retailer name.
-			points += 1
+			points += rs.RetailerCharacterPoints
 		}
 	}
 
-	if math.Mod(r.Total, 1.0) < 0.01 {
+	return points
+}
+
+func (rs *Ruleset) roundDollarPoints(r *Receipt) int {
+	if math.Mod(r.BaseAmount(r.Total), 1.0) < 0.01 {
 		// 50 points if the total is a round dollar amount with no cents.
-		points += 50
+		return rs.RoundDollarPoints
 	}
+	return 0
+}
 
-	if math.Mod(r.Total, 0.25) < 0.01 {
+func (rs *Ruleset) quarterMultiplePoints(r *Receipt) int {
+	if math.Mod(r.BaseAmount(r.Total), 0.25) < 0.01 {
 		// 25 points if the total is a multiple of 0.25.
-		points += 25
+		return rs.QuarterMultiplePoints
+	}
+	return 0
+}
+
+func (rs *Ruleset) itemPairPoints(r *Receipt) int {
+	var units int
+	for _, item := range r.Items {
+		units += item.units()
 	}
 
-	// 5 points for every two items on the receipt.
-	points += len(r.Items) / 2 * 5
+	// 5 points for every two items on the receipt. A line with a quantity
+	// counts once per unit, the same as listing each unit separately.
+	return units / 2 * rs.ItemPairPoints
+}
+
+func (rs *Ruleset) itemDescriptionPoints(r *Receipt) int {
+	var points int
 
 	for _, item := range r.Items {
-		trimmedDesc := strings.TrimSpace(item.ShortDescription)
+		trimmedDesc := strings.TrimSpace(norm.NFC.String(item.ShortDescription))
 
-		if len(trimmedDesc)%3 == 0 {
+		// The length is measured in characters, not bytes, so non-ASCII
+		// descriptions are treated the same as ASCII ones.
+		if utf8.RuneCountInString(trimmedDesc)%rs.DescriptionLengthMultiple == 0 {
 			// If the trimmed length of the item description is a multiple of 3,
 			// multiply the price by 0.2 and round up to the nearest integer.
-			// The result is the number of points earned.
-			points += int(math.Ceil(item.Price * 0.2))
+			// The result is the number of points earned. Each unit on the line
+			// earns points for its own price.
+			unitPoints := int(math.Ceil(
+				r.BaseAmount(item.pricePerUnit()) * rs.DescriptionPriceMultiplier,
+			))
+			points += unitPoints * item.units()
 		}
 	}
 
+	return points
+}
+
+func (rs *Ruleset) oddDayPoints(r *Receipt) int {
 	if r.PurchaseDateTime.Day()%2 == 1 {
 		// 6 points if the day in the purchase date is odd.
-		points += 6
+		return rs.OddDayPoints
 	}
+	return 0
+}
 
+func (rs *Ruleset) afternoonPoints(r *Receipt) int {
 	twoPM := time.Date(
 		r.PurchaseDateTime.Year(),
 		r.PurchaseDateTime.Month(),
 		r.PurchaseDateTime.Day(),
-		14,
+		rs.AfternoonStartHour,
 		0,
 		0,
 		0,
 		r.PurchaseDateTime.Location(),
 	)
 
-	fourPM := twoPM.Add(2 * time.Hour)
+	fourPM := twoPM.Add(time.Duration(rs.AfternoonEndHour-rs.AfternoonStartHour) * time.Hour)
 
 	if r.PurchaseDateTime.After(twoPM) && r.PurchaseDateTime.Before(fourPM) {
 		// 10 points if the time of purchase is after 2:00pm and before 4:00pm.
-		points += 10
+		return rs.AfternoonPoints
 	}
+	return 0
+}
 
-	points += uniqueNamePoints(r)
+func (rs *Ruleset) uniqueNamePoints(r *Receipt) int {
+	// Lines are the same product if they share a UPC or SKU, or if neither
+	// has one, a description and unit price. A single line with a quantity
+	// is still one product.
+	seen := make(map[string]bool)
+	for _, item := range r.Items {
+		key := item.productKey()
+		if seen[key] {
+			return 0
+		}
+		seen[key] = true
+	}
 
-	return points
+	// 20 points if all items are unique
+	return rs.UniqueItemsPoints
+}
+
+// PointsBreakdown returns the points awarded by each rule of the default
+// ruleset, in the order the rules are applied.
+func PointsBreakdown(r *Receipt) []RulePoints {
+	return DefaultRuleset.PointsBreakdown(r)
+}
+
+func CountPoints(r *Receipt) int {
+	return DefaultRuleset.CountPoints(r)
 }
@@ -31,11 +31,67 @@ func TestUniquePoints(t *testing.T) {
 		},
 	}
 
-	if points := uniqueNamePoints(&uniqueReceipt); points != 20 {
+	if points := DefaultRuleset.uniqueNamePoints(&uniqueReceipt); points != 20 {
 		t.Fatal("Unexpected number of points")
 	}
 
-	if points := uniqueNamePoints(&nonUniqueReceipt); points != 0 {
+	if points := DefaultRuleset.uniqueNamePoints(&nonUniqueReceipt); points != 0 {
 		t.Fatal("Unexpected number of points")
 	}
 }
+
+// Items that share a description but not a price are still unique. Before
+// the price map was initialised, scoring any receipt with items panicked.
+func TestUniquePointsAllowsRepeatedDescriptions(t *testing.T) {
+	differentPrices := Receipt{
+		Items: []Item{
+			{ShortDescription: "a", Price: 1.00},
+			{ShortDescription: "a", Price: 2.00},
+		},
+	}
+
+	samePrices := Receipt{
+		Items: []Item{
+			{ShortDescription: "a", Price: 1.00},
+			{ShortDescription: "a", Price: 1.00},
+		},
+	}
+
+	if points := CountPoints(&differentPrices) - CountPoints(&samePrices); points != 20 {
+		t.Fatalf("Wrong unique items bonus: '%d' expected '%d'", points, 20)
+	}
+}
+
+func TestUniquePointsByProduct(t *testing.T) {
+	sameSKU := Receipt{
+		Items: []Item{
+			{ShortDescription: "Gatorade", SKU: "GAT-20OZ"},
+			{ShortDescription: "Gatorade Cool Blue", SKU: "GAT-20OZ"},
+		},
+	}
+
+	differentUPCs := Receipt{
+		Items: []Item{
+			{ShortDescription: "Gatorade", UPC: "052000328660"},
+			{ShortDescription: "Gatorade", UPC: "052000338669"},
+		},
+	}
+
+	oneLine := Receipt{
+		Items: []Item{
+			{ShortDescription: "Gatorade", Price: 6.75, Quantity: 3},
+		},
+	}
+
+	if points := DefaultRuleset.uniqueNamePoints(&sameSKU); points != 0 {
+		t.Fatalf("Wrong points for a repeated SKU: '%d' expected '%d'", points, 0)
+	}
+
+	if points := DefaultRuleset.uniqueNamePoints(&differentUPCs); points != 20 {
+		t.Fatalf("Wrong points for different UPCs: '%d' expected '%d'", points, 20)
+	}
+
+	if points := DefaultRuleset.uniqueNamePoints(&oneLine); points != 20 {
+		t.Fatalf("Wrong points for a single line: '%d' expected '%d'", points, 20)
+	}
+}
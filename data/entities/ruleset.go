@@ -0,0 +1,117 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidRuleset = errors.New("invalid ruleset")
+
+// Ruleset holds the parameters of the points rules. The comments on each
+// rule describe it with the DefaultRuleset values.
+type Ruleset struct {
+	// RetailerCharacterPoints are awarded per alphanumeric character in the
+	// retailer name.
+	RetailerCharacterPoints int `json:"retailerCharacterPoints"`
+	// RoundDollarPoints are awarded if the total has no cents.
+	RoundDollarPoints int `json:"roundDollarPoints"`
+	// QuarterMultiplePoints are awarded if the total is a multiple of 0.25.
+	QuarterMultiplePoints int `json:"quarterMultiplePoints"`
+	// ItemPairPoints are awarded per two items.
+	ItemPairPoints int `json:"itemPairPoints"`
+	// Items whose trimmed description length is a multiple of
+	// DescriptionLengthMultiple earn their price times
+	// DescriptionPriceMultiplier, rounded up.
+	DescriptionLengthMultiple  int     `json:"descriptionLengthMultiple"`
+	DescriptionPriceMultiplier float64 `json:"descriptionPriceMultiplier"`
+	// OddDayPoints are awarded if the purchase day is odd.
+	OddDayPoints int `json:"oddDayPoints"`
+	// AfternoonPoints are awarded for purchases strictly between
+	// AfternoonStartHour and AfternoonEndHour.
+	AfternoonPoints    int `json:"afternoonPoints"`
+	AfternoonStartHour int `json:"afternoonStartHour"`
+	AfternoonEndHour   int `json:"afternoonEndHour"`
+	// UniqueItemsPoints are awarded if no product appears on two lines.
+	UniqueItemsPoints int `json:"uniqueItemsPoints"`
+}
+
+// DefaultRuleset is the ruleset receipts are scored with.
+var DefaultRuleset = Ruleset{
+	RetailerCharacterPoints:    1,
+	RoundDollarPoints:          50,
+	QuarterMultiplePoints:      25,
+	ItemPairPoints:             5,
+	DescriptionLengthMultiple:  3,
+	DescriptionPriceMultiplier: 0.2,
+	OddDayPoints:               6,
+	AfternoonPoints:            10,
+	AfternoonStartHour:         14,
+	AfternoonEndHour:           16,
+	UniqueItemsPoints:          20,
+}
+
+// ParseRuleset decodes a ruleset from JSON. Parameters left out keep their
+// DefaultRuleset values, so a candidate only needs to list what it changes.
+func ParseRuleset(b []byte) (*Ruleset, error) {
+	ruleset := DefaultRuleset
+	if err := json.Unmarshal(b, &ruleset); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRuleset, err)
+	}
+
+	if err := ruleset.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &ruleset, nil
+}
+
+// Validate reports parameters that would make the rules misbehave, such as
+// negative points.
+func (rs *Ruleset) Validate() error {
+	points := []int{
+		rs.RetailerCharacterPoints, rs.RoundDollarPoints,
+		rs.QuarterMultiplePoints, rs.ItemPairPoints, rs.OddDayPoints,
+		rs.AfternoonPoints, rs.UniqueItemsPoints,
+	}
+
+	for _, p := range points {
+		if p < 0 {
+			return fmt.Errorf("%w: points can't be negative", ErrInvalidRuleset)
+		}
+	}
+
+	switch {
+	case rs.DescriptionLengthMultiple < 1:
+		return fmt.Errorf("%w: descriptionLengthMultiple must be at least 1", ErrInvalidRuleset)
+	case rs.DescriptionPriceMultiplier < 0:
+		return fmt.Errorf("%w: descriptionPriceMultiplier can't be negative", ErrInvalidRuleset)
+	case rs.AfternoonStartHour < 0 || rs.AfternoonEndHour > 24 ||
+		rs.AfternoonStartHour >= rs.AfternoonEndHour:
+		return fmt.Errorf("%w: afternoon hours must be within 0-24, start before end", ErrInvalidRuleset)
+	}
+
+	return nil
+}
+
+// PointsBreakdown returns the points awarded by each rule, in the order the
+// rules are applied.
+func (rs *Ruleset) PointsBreakdown(r *Receipt) []RulePoints {
+	breakdown := make([]RulePoints, len(rules))
+
+	for i, rule := range rules {
+		breakdown[i] = RulePoints{Rule: rule.name, Points: rule.points(rs, r)}
+	}
+
+	return breakdown
+}
+
+func (rs *Ruleset) CountPoints(r *Receipt) int {
+	var points int
+
+	for _, rp := range rs.PointsBreakdown(r) {
+		points += rp.Points
+	}
+
+	return points
+}
@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRuleset(t *testing.T) {
+	ruleset, err := ParseRuleset([]byte(`{"roundDollarPoints": 100}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ruleset.RoundDollarPoints != 100 || ruleset.QuarterMultiplePoints != 25 {
+		t.Fatalf("Wrong ruleset: %+v", ruleset)
+	}
+
+	receipt := Receipt{
+		Retailer:         "Target",
+		PurchaseDateTime: time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC),
+		Total:            10.00,
+	}
+
+	// Only the round dollar rule changed, by 50 points.
+	if delta := ruleset.CountPoints(&receipt) - CountPoints(&receipt); delta != 50 {
+		t.Fatalf("Wrong difference in points: '%d' expected '%d'", delta, 50)
+	}
+
+	invalid := []string{
+		`{"itemPairPoints": -5}`,
+		`{"descriptionLengthMultiple": 0}`,
+		`{"afternoonStartHour": 16, "afternoonEndHour": 14}`,
+		`{"afternoonEndHour": 25}`,
+		`{"roundDollarPoints": "lots"}`,
+	}
+
+	for _, b := range invalid {
+		if _, err := ParseRuleset([]byte(b)); !errors.Is(err, ErrInvalidRuleset) {
+			t.Fatalf("Wrong error for %s: %v", b, err)
+		}
+	}
+}
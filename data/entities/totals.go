@@ -0,0 +1,124 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/vimolicious/receipt-processor/data/currency"
+)
+
+// Discount is a coupon or markdown taken off the receipt. Amount is positive
+// and is subtracted from the item prices.
+type Discount struct {
+	Description string
+	Amount      float64
+}
+
+// DEFAULT_TOTAL_TOLERANCE is how far, in the receipt's currency, the total
+// may be from the amount its lines add up to. Zero only allows the rounding
+// of the smallest minor unit of any currency.
+const DEFAULT_TOTAL_TOLERANCE = 0.0
+
+var totalTolerance = DEFAULT_TOTAL_TOLERANCE
+
+// SetTotalTolerance changes how far totals may be from the amount their
+// lines add up to, e.g. to allow for tax rounded per line. It should be
+// called during startup.
+func SetTotalTolerance(tolerance float64) {
+	totalTolerance = tolerance
+}
+
+// TotalsError explains why a receipt's amounts don't add up.
+type TotalsError struct {
+	Field    string
+	Actual   float64
+	Expected float64
+	// Explanation lists the amounts Expected was added up from.
+	Explanation string
+	Currency    string
+}
+
+func (e *TotalsError) Error() string {
+	return fmt.Sprintf(
+		"wrong value in '%s': got %s, expected %s (%s)",
+		e.Field,
+		currency.FormatAmount(e.Actual, e.Currency),
+		currency.FormatAmount(e.Expected, e.Currency),
+		e.Explanation,
+	)
+}
+
+// sameAmount compares amounts to a thousandth, the smallest minor unit of any
+// supported currency, allowing for the configured tolerance.
+func sameAmount(a, b float64) bool {
+	difference := math.Abs(math.Round(a*1000) - math.Round(b*1000))
+	return difference <= math.Round(totalTolerance*1000)
+}
+
+// ItemsSubtotal returns the sum of the item prices.
+func ItemsSubtotal(r *Receipt) float64 {
+	var subtotal float64
+	for _, item := range r.Items {
+		subtotal += item.Price
+	}
+	return subtotal
+}
+
+// DiscountsTotal returns the sum of the discounts.
+func DiscountsTotal(r *Receipt) float64 {
+	var discounts float64
+	for _, discount := range r.Discounts {
+		discounts += discount.Amount
+	}
+	return discounts
+}
+
+// ReconcileTotals checks the receipt's amounts add up, in the receipt's own
+// currency: the subtotal, if listed, is the sum of the item prices, and the
+// total is the item prices less discounts plus tax and tip. It returns a
+// *TotalsError explaining the first mismatch.
+func ReconcileTotals(r *Receipt) error {
+	subtotal := ItemsSubtotal(r)
+
+	if r.Subtotal != nil && !sameAmount(*r.Subtotal, subtotal) {
+		return &TotalsError{
+			Field:       "subtotal",
+			Actual:      *r.Subtotal,
+			Expected:    subtotal,
+			Explanation: "sum of item prices",
+			Currency:    r.Currency,
+		}
+	}
+
+	terms := []string{"items " + currency.FormatAmount(subtotal, r.Currency)}
+	expected := subtotal
+
+	if len(r.Discounts) > 0 {
+		discounts := DiscountsTotal(r)
+		expected -= discounts
+		terms = append(terms, "- discounts "+currency.FormatAmount(discounts, r.Currency))
+	}
+
+	if r.Tax != nil {
+		expected += *r.Tax
+		terms = append(terms, "+ tax "+currency.FormatAmount(*r.Tax, r.Currency))
+	}
+
+	if r.Tip != nil {
+		expected += *r.Tip
+		terms = append(terms, "+ tip "+currency.FormatAmount(*r.Tip, r.Currency))
+	}
+
+	if !sameAmount(r.Total, expected) {
+		return &TotalsError{
+			Field:       "total",
+			Actual:      r.Total,
+			Expected:    expected,
+			Explanation: strings.Join(terms, " "),
+			Currency:    r.Currency,
+		}
+	}
+
+	return nil
+}
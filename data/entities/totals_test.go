@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+)
+
+func amount(a float64) *float64 {
+	return &a
+}
+
+func TestReconcileTotals(t *testing.T) {
+	items := []Item{{Price: 6.49}, {Price: 12.25}}
+
+	cases := []struct {
+		description string
+		receipt     Receipt
+		field       string
+	}{
+		{"items only", Receipt{Items: items, Total: 18.74}, ""},
+		{"wrong total", Receipt{Items: items, Total: 18.75}, "total"},
+		{"subtotal", Receipt{Items: items, Subtotal: amount(18.74), Total: 18.74}, ""},
+		{"wrong subtotal", Receipt{Items: items, Subtotal: amount(18.00), Total: 18.74}, "subtotal"},
+		{"zero subtotal", Receipt{Items: items, Subtotal: amount(0), Total: 18.74}, "subtotal"},
+		{
+			"tax, tip and discounts",
+			Receipt{
+				Items:     items,
+				Discounts: []Discount{{"Coupon", 1.00}, {"Member", 0.50}},
+				Tax:       amount(1.51),
+				Tip:       amount(3.00),
+				Total:     21.75,
+			},
+			"",
+		},
+		{"tax left out", Receipt{Items: items, Tax: amount(1.51), Total: 18.74}, "total"},
+		{"three decimals", Receipt{Items: []Item{{Price: 1.125}}, Total: 1.125, Currency: "BHD"}, ""},
+	}
+
+	for _, c := range cases {
+		err := ReconcileTotals(&c.receipt)
+
+		var totalsError *TotalsError
+		if c.field == "" && err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.description, err)
+		}
+
+		if c.field != "" && (!errors.As(err, &totalsError) || totalsError.Field != c.field) {
+			t.Fatalf("%s: wrong error: '%v' expected a mismatch in '%s'", c.description, err, c.field)
+		}
+	}
+}
+
+func TestTotalTolerance(t *testing.T) {
+	receipt := Receipt{Items: []Item{{Price: 10.00}}, Tax: amount(0.83), Total: 10.84}
+
+	if err := ReconcileTotals(&receipt); err == nil {
+		t.Fatal("Total a cent out was accepted without a tolerance")
+	}
+
+	SetTotalTolerance(0.01)
+	defer SetTotalTolerance(DEFAULT_TOTAL_TOLERANCE)
+
+	if err := ReconcileTotals(&receipt); err != nil {
+		t.Fatalf("Total a cent out rejected with a tolerance: %s", err)
+	}
+
+	receipt.Total = 10.85
+	if err := ReconcileTotals(&receipt); err == nil {
+		t.Fatal("Total two cents out was accepted with a one cent tolerance")
+	}
+}
@@ -0,0 +1,60 @@
+package events
+
+import "sync"
+
+type Handler func(Event)
+
+type subscription struct {
+	handler Handler
+	types   map[Type]bool
+}
+
+// Broker is an in-process Publisher that delivers each event synchronously to
+// every subscribed handler. Handlers should return quickly since they block
+// the relay.
+type Broker struct {
+	subscriptions map[*subscription]struct{}
+	mutex         sync.RWMutex
+}
+
+func NewBroker() *Broker {
+	broker := Broker{
+		subscriptions: make(map[*subscription]struct{}),
+	}
+	return &broker
+}
+
+func (b *Broker) Publish(event Event) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for s := range b.subscriptions {
+		if len(s.types) == 0 || s.types[event.Type] {
+			s.handler(event)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe registers a handler for the given event types, or for every event
+// if no types are given. The returned function removes the subscription.
+func (b *Broker) Subscribe(handler Handler, types ...Type) func() {
+	s := &subscription{
+		handler: handler,
+		types:   make(map[Type]bool, len(types)),
+	}
+	for _, t := range types {
+		s.types[t] = true
+	}
+
+	b.mutex.Lock()
+	b.subscriptions[s] = struct{}{}
+	b.mutex.Unlock()
+
+	return func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subscriptions, s)
+	}
+}
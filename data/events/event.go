@@ -0,0 +1,38 @@
+package events
+
+import (
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+type Type string
+
+const (
+	ReceiptAdded Type = "receipt.added"
+	// ReceiptImported is a historical receipt added by a bulk import. It's
+	// kept apart from ReceiptAdded so imports don't flood live feeds.
+	ReceiptImported Type = "receipt.imported"
+	ReceiptRescored Type = "receipt.rescored"
+)
+
+// Event is a domain event describing a state change in the receipt
+// repository. Sequence is assigned by the outbox and increases monotonically.
+type Event struct {
+	Sequence       uint64
+	Type           Type
+	Receipt        entities.Receipt
+	PreviousPoints int
+	OccurredAt     time.Time
+}
+
+type Publisher interface {
+	Publish(Event) error
+}
+
+// Outbox is implemented by repositories that record events in the same
+// write as the state change they describe.
+type Outbox interface {
+	PendingEvents(limit int) ([]Event, error)
+	MarkPublished(sequence uint64) error
+}
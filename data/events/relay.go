@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const RELAY_BATCH_SIZE = 100
+
+// Relay drains an Outbox into a Publisher. Events are published in order and
+// only marked as published once the publisher accepts them, so an unavailable
+// publisher delays delivery instead of losing events.
+type Relay struct {
+	outbox    Outbox
+	publisher Publisher
+	interval  time.Duration
+}
+
+func NewRelay(o Outbox, p Publisher, interval time.Duration) *Relay {
+	relay := Relay{
+		outbox:    o,
+		publisher: p,
+		interval:  interval,
+	}
+	return &relay
+}
+
+// Run drains the outbox every interval until the context is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Drain(); err != nil {
+			log.Printf("Event relay: %s\n", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain publishes every pending event, stopping at the first failure.
+func (r *Relay) Drain() error {
+	for {
+		pending, err := r.outbox.PendingEvents(RELAY_BATCH_SIZE)
+		if err != nil {
+			return err
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		for _, event := range pending {
+			if err := r.publisher.Publish(event); err != nil {
+				return err
+			}
+
+			if err := r.outbox.MarkPublished(event.Sequence); err != nil {
+				return err
+			}
+		}
+	}
+}
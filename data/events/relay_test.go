@@ -0,0 +1,98 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+// flakyPublisher fails while down is set and forwards to a Broker otherwise.
+type flakyPublisher struct {
+	broker *events.Broker
+	down   bool
+}
+
+func (p *flakyPublisher) Publish(e events.Event) error {
+	if p.down {
+		return errors.New("publisher unavailable")
+	}
+	return p.broker.Publish(e)
+}
+
+func addReceipts(t *testing.T, repo *inmemory.InMemoryReceiptRepository, n int) {
+	for i := 0; i < n; i++ {
+		receipt := entities.Receipt{Id: uuid.New(), Retailer: "Target"}
+		if err := repo.AddReceipt(&receipt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRelayDeliversAfterOutage(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	repo.EnableOutbox()
+	broker := events.NewBroker()
+	publisher := &flakyPublisher{broker: broker, down: true}
+	relay := events.NewRelay(repo, publisher, 0)
+
+	received := make([]events.Event, 0)
+	broker.Subscribe(func(e events.Event) {
+		received = append(received, e)
+	}, events.ReceiptAdded)
+
+	addReceipts(t, repo, 3)
+
+	if err := relay.Drain(); err == nil {
+		t.Fatal("Expected an error while the publisher is down")
+	}
+
+	if len(received) != 0 {
+		t.Fatalf("Unexpected events delivered: %d", len(received))
+	}
+
+	publisher.down = false
+	if err := relay.Drain(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("Wrong number of events: '%d' expected '3'", len(received))
+	}
+
+	for i, e := range received {
+		if e.Sequence != uint64(i+1) || e.Type != events.ReceiptAdded {
+			t.Fatalf("Unexpected event at %d: %+v", i, e)
+		}
+	}
+
+	pending, err := repo.PendingEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 0 {
+		t.Fatalf("Outbox not drained: %d events pending", len(pending))
+	}
+}
+
+func TestBrokerFiltersAndUnsubscribes(t *testing.T) {
+	broker := events.NewBroker()
+
+	var added, all int
+	unsubscribe := broker.Subscribe(func(events.Event) { added++ }, events.ReceiptAdded)
+	broker.Subscribe(func(events.Event) { all++ })
+
+	broker.Publish(events.Event{Type: events.ReceiptAdded})
+	broker.Publish(events.Event{Type: events.ReceiptRescored})
+
+	unsubscribe()
+	broker.Publish(events.Event{Type: events.ReceiptAdded})
+
+	if added != 1 || all != 3 {
+		t.Fatalf("Unexpected deliveries: added=%d all=%d", added, all)
+	}
+}
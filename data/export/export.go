@@ -0,0 +1,193 @@
+// Package export writes stored receipts as CSV for spreadsheets.
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/currency"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+)
+
+var ErrUnknownKind = errors.New("unknown export kind")
+
+type Kind string
+
+const (
+	// Receipts exports one row per receipt.
+	Receipts Kind = "receipts"
+	// Items exports one row per item, with the id of its receipt.
+	Items Kind = "items"
+)
+
+// ERROR_TRAILER is the HTTP trailer an export that fails part way is
+// reported in, since the status has already been sent by then.
+const ERROR_TRAILER = "Export-Error"
+
+// PAGE_SIZE is how many receipts are read from the repository at a time.
+// Rows are flushed after each page, so exports never hold more.
+const PAGE_SIZE = 500
+
+// Columns are only ever added at the end so spreadsheets built on an export
+// keep working.
+var RECEIPT_COLUMNS = []string{
+	"id", "retailer", "retailerId", "purchaseDate", "purchaseTime", "timezone",
+	"currency", "itemCount", "subtotal", "discounts", "tax", "tip", "total",
+	"points", "accountId",
+}
+
+var ITEM_COLUMNS = []string{
+	"receiptId", "line", "shortDescription", "quantity", "unitPrice", "price",
+	"sku", "upc", "currency",
+}
+
+// ParseKind returns the kind with the given name.
+func ParseKind(name string) (Kind, error) {
+	switch kind := Kind(name); kind {
+	case Receipts, Items:
+		return kind, nil
+	}
+
+	return "", fmt.Errorf("%w '%s'", ErrUnknownKind, name)
+}
+
+// ParseTime parses a filter bound given as an RFC 3339 timestamp or a
+// YYYY-MM-DD date, which is midnight UTC.
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' isn't a YYYY-MM-DD date or RFC 3339 time", value)
+	}
+
+	return t, nil
+}
+
+// Write streams the stored receipts matching the filter to w as CSV, in the
+// order they were added, starting with a header row.
+func Write(w io.Writer, repo repositories.ReceiptRepository, kind Kind, filter repositories.ReceiptFilter) error {
+	columns, toRows := RECEIPT_COLUMNS, receiptRows
+	if kind == Items {
+		columns, toRows = ITEM_COLUMNS, itemRows
+	} else if kind != Receipts {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	for after := uuid.Nil; ; {
+		page, err := repo.ListReceipts(filter, after, PAGE_SIZE)
+		if err != nil {
+			return err
+		}
+
+		for _, receipt := range page {
+			if err := writer.WriteAll(toRows(receipt)); err != nil {
+				return err
+			}
+		}
+
+		// WriteAll flushes, but not for an empty page.
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+
+		if len(page) < PAGE_SIZE {
+			return nil
+		}
+		after = page[len(page)-1].Id
+	}
+}
+
+func receiptRows(r *entities.Receipt) [][]string {
+	var discounts *float64
+	if len(r.Discounts) > 0 {
+		total := entities.DiscountsTotal(r)
+		discounts = &total
+	}
+
+	return [][]string{{
+		r.Id.String(),
+		text(r.Retailer),
+		r.RetailerId,
+		r.PurchaseDateTime.Format("2006-01-02"),
+		r.PurchaseDateTime.Format("15:04"),
+		r.PurchaseDateTime.Location().String(),
+		currencyCode(r),
+		strconv.Itoa(len(r.Items)),
+		optionalAmount(r.Subtotal, r.Currency),
+		optionalAmount(discounts, r.Currency),
+		optionalAmount(r.Tax, r.Currency),
+		optionalAmount(r.Tip, r.Currency),
+		currency.FormatAmount(r.Total, r.Currency),
+		strconv.Itoa(r.Points),
+		r.AccountId,
+	}}
+}
+
+func itemRows(r *entities.Receipt) [][]string {
+	rows := make([][]string, len(r.Items))
+	for i, item := range r.Items {
+		quantity := item.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+
+		var unitPrice *float64
+		if item.UnitPrice != 0 {
+			unitPrice = &item.UnitPrice
+		}
+
+		rows[i] = []string{
+			r.Id.String(),
+			strconv.Itoa(i + 1),
+			text(item.ShortDescription),
+			strconv.Itoa(quantity),
+			optionalAmount(unitPrice, r.Currency),
+			currency.FormatAmount(item.Price, r.Currency),
+			text(item.SKU),
+			item.UPC,
+			currencyCode(r),
+		}
+	}
+	return rows
+}
+
+// currencyCode names the base currency instead of leaving it empty, so
+// every row says what its amounts are in.
+func currencyCode(r *entities.Receipt) string {
+	if r.Currency == "" {
+		return currency.BaseCurrency()
+	}
+	return strings.ToUpper(r.Currency)
+}
+
+// optionalAmount leaves amounts the receipt didn't list empty.
+func optionalAmount(amount *float64, code string) string {
+	if amount == nil {
+		return ""
+	}
+	return currency.FormatAmount(*amount, code)
+}
+
+// text quotes free text that a spreadsheet would otherwise run as a formula.
+func text(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+func readCSV(t *testing.T, b *bytes.Buffer) [][]string {
+	rows, err := csv.NewReader(b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestWrite(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tax := 36.0
+	jpy := &entities.Receipt{
+		Id:               uuid.New(),
+		Retailer:         "=Lawson, Shibuya",
+		PurchaseDateTime: time.Date(2024, 6, 1, 9, 5, 0, 0, tokyo),
+		Currency:         "JPY",
+		Items: []entities.Item{
+			{ShortDescription: "Onigiri", Price: 300, Quantity: 2, UnitPrice: 150, SKU: "ONI-1"},
+			{ShortDescription: "Tea \"green\"", Price: 160},
+		},
+		Tax:    &tax,
+		Total:  496,
+		Points: 12,
+	}
+
+	usd := &entities.Receipt{
+		Id:               uuid.New(),
+		Retailer:         "Target",
+		RetailerId:       "target",
+		PurchaseDateTime: time.Date(2024, 6, 2, 13, 1, 0, 0, time.UTC),
+		Items:            []entities.Item{{ShortDescription: "Gatorade", Price: 2.25}},
+		Discounts:        []entities.Discount{{Description: "Coupon", Amount: 0.5}},
+		Total:            1.75,
+		Points:           40,
+		AccountId:        "acct-1",
+	}
+
+	for _, r := range []*entities.Receipt{jpy, usd} {
+		if err := repo.AddReceipt(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var receipts bytes.Buffer
+	if err := Write(&receipts, repo, Receipts, repositories.ReceiptFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		RECEIPT_COLUMNS,
+		{jpy.Id.String(), "'=Lawson, Shibuya", "", "2024-06-01", "09:05", "Asia/Tokyo", "JPY", "2", "", "", "36", "", "496", "12", ""},
+		{usd.Id.String(), "Target", "target", "2024-06-02", "13:01", "UTC", "USD", "1", "", "0.50", "", "", "1.75", "40", "acct-1"},
+	}
+
+	if rows := readCSV(t, &receipts); !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("Wrong receipt rows: %q expected %q", rows, expected)
+	}
+
+	var items bytes.Buffer
+	filter := repositories.ReceiptFilter{Retailer: "=lawson, shibuya"}
+	if err := Write(&items, repo, Items, filter); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = [][]string{
+		ITEM_COLUMNS,
+		{jpy.Id.String(), "1", "Onigiri", "2", "150", "300", "ONI-1", "", "JPY"},
+		{jpy.Id.String(), "2", "Tea \"green\"", "1", "", "160", "", "", "JPY"},
+	}
+
+	if rows := readCSV(t, &items); !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("Wrong item rows: %q expected %q", rows, expected)
+	}
+
+	if err := Write(&items, repo, "payments", filter); !errors.Is(err, ErrUnknownKind) {
+		t.Fatalf("Wrong error: '%v' expected '%v'", err, ErrUnknownKind)
+	}
+}
+
+// Exports span pages without repeating or skipping receipts.
+func TestWritePages(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	for i := 0; i < PAGE_SIZE+1; i++ {
+		receipt := entities.Receipt{Id: uuid.New(), Retailer: "Target"}
+		if err := repo.AddReceipt(&receipt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, repo, Receipts, repositories.ReceiptFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readCSV(t, &b)
+	if len(rows) != PAGE_SIZE+2 {
+		t.Fatalf("Wrong number of rows: '%d' expected '%d'", len(rows), PAGE_SIZE+2)
+	}
+
+	seen := make(map[string]bool)
+	for _, row := range rows[1:] {
+		if seen[row[0]] {
+			t.Fatalf("Receipt '%s' exported twice", row[0])
+		}
+		seen[row[0]] = true
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	date, err := ParseTime("2024-06-10")
+	if err != nil || !date.Equal(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Wrong time: '%s' (%v)", date, err)
+	}
+
+	if _, err := ParseTime("2024-06-10T09:00:00-05:00"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseTime("last week"); err == nil {
+		t.Fatal("Parsed an invalid time")
+	}
+}
+
+func TestColumnsAreStable(t *testing.T) {
+	// Appending columns is fine; reordering or renaming breaks spreadsheets.
+	receipts := "id,retailer,retailerId,purchaseDate,purchaseTime,timezone,currency," +
+		"itemCount,subtotal,discounts,tax,tip,total,points,accountId"
+	items := "receiptId,line,shortDescription,quantity,unitPrice,price,sku,upc,currency"
+
+	if got := strings.Join(RECEIPT_COLUMNS, ","); !strings.HasPrefix(got, receipts) {
+		t.Fatalf("Receipt columns changed: '%s' expected to start with '%s'", got, receipts)
+	}
+
+	if got := strings.Join(ITEM_COLUMNS, ","); !strings.HasPrefix(got, items) {
+		t.Fatalf("Item columns changed: '%s' expected to start with '%s'", got, items)
+	}
+}
@@ -0,0 +1,299 @@
+// Package importer loads historical receipts from CSV or JSON files into a
+// repository. Every receipt goes through the same validation as the API, and
+// rejected records are reported with the reason instead of stopping the
+// import.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+	"github.com/vimolicious/receipt-processor/data/transform"
+)
+
+var ErrInvalidMapping = errors.New("invalid column mapping")
+var ErrUnknownFormat = errors.New("unknown import format")
+
+type Format string
+
+const (
+	CSV Format = "csv"
+	// JSON reads a sequence of receipts, so it covers both a JSON document
+	// and NDJSON.
+	JSON Format = "json"
+)
+
+// MAX_REPORTED_REJECTIONS bounds how many rejections a report lists. The
+// rest are only counted.
+const MAX_REPORTED_REJECTIONS = 1000
+
+// ID_NAMESPACE derives receipt ids from original ids that aren't UUIDs, so
+// importing the same file twice doesn't store its receipts twice.
+var ID_NAMESPACE = uuid.MustParse("5b0c1f6e-2d55-4e0b-9a0f-3c8e41a6b7d2")
+
+// Rejection explains why a record wasn't imported.
+type Rejection struct {
+	// Record is the line a CSV receipt starts on, or the position of a
+	// JSON receipt in its file, counting from 1.
+	Record int    `json:"record"`
+	Id     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type Report struct {
+	Read       int         `json:"read"`
+	Imported   int         `json:"imported"`
+	Rejected   int         `json:"rejected"`
+	Rejections []Rejection `json:"rejections"`
+}
+
+func (r *Report) reject(record int, id string, err error) {
+	r.Rejected++
+	if len(r.Rejections) < MAX_REPORTED_REJECTIONS {
+		r.Rejections = append(r.Rejections, Rejection{
+			Record: record,
+			Id:     id,
+			Reason: err.Error(),
+		})
+	}
+}
+
+// Importer stores imported receipts in a repository, scored with a ruleset.
+// Promotions aren't applied to historical receipts.
+type Importer struct {
+	repo    repositories.ReceiptRepository
+	ruleset *entities.Ruleset
+}
+
+func NewImporter(repo repositories.ReceiptRepository, ruleset *entities.Ruleset) *Importer {
+	newImporter := &Importer{
+		repo:    repo,
+		ruleset: ruleset,
+	}
+	return newImporter
+}
+
+// Import reads receipts in the format from r. The mapping is only used for
+// CSV. An error means the import stopped early, e.g. because the repository
+// failed; the report covers the records read until then.
+func (im *Importer) Import(r io.Reader, format Format, mapping Mapping) (Report, error) {
+	switch format {
+	case CSV:
+		return im.ImportCSV(r, mapping)
+	case JSON:
+		return im.ImportJSON(r)
+	}
+
+	return Report{Rejections: []Rejection{}}, fmt.Errorf("%w '%s'", ErrUnknownFormat, format)
+}
+
+// ImportJSON reads a JSON receipt or a sequence of them, such as NDJSON.
+// Receipts may have an "id" to keep.
+func (im *Importer) ImportJSON(r io.Reader) (Report, error) {
+	report := Report{Rejections: []Rejection{}}
+
+	decoder := json.NewDecoder(r)
+	for record := 1; ; record++ {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+
+		var syntaxError *json.SyntaxError
+		if err != nil && !errors.As(err, &syntaxError) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return report, err
+		}
+
+		report.Read++
+
+		if err != nil {
+			// The rest of the stream can't be recovered after a syntax error.
+			report.reject(record, "", err)
+			return report, nil
+		}
+
+		var original struct {
+			Id string `json:"id"`
+		}
+		json.Unmarshal(raw, &original)
+
+		if err := im.importReceipt(&report, record, original.Id, raw); err != nil {
+			return report, err
+		}
+	}
+}
+
+// importReceipt validates, scores and stores one receipt, recording it in
+// the report. It only returns errors that should stop the import.
+func (im *Importer) importReceipt(report *Report, record int, originalId string, raw []byte) error {
+	receipt, err := im.toEntity(originalId, raw)
+	if err != nil {
+		report.reject(record, originalId, err)
+		return nil
+	}
+
+	err = im.repo.AddReceipt(receipt)
+	if errors.Is(err, repositories.ErrAlreadyExists) {
+		report.reject(record, originalId, fmt.Errorf("receipt %s was already imported", receipt.Id))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	report.Imported++
+	return nil
+}
+
+func (im *Importer) toEntity(originalId string, raw []byte) (*entities.Receipt, error) {
+	var receiptModel models.Receipt
+	if err := json.Unmarshal(raw, &receiptModel); err != nil {
+		return nil, err
+	}
+
+	receipt, err := transform.ReceiptModelToEntity(&receiptModel)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := entities.ReconcileTotals(receipt); err != nil {
+		return nil, err
+	}
+
+	if originalId != "" {
+		receipt.Id = receiptId(originalId)
+	}
+	receipt.Points = im.ruleset.CountPoints(receipt)
+	receipt.Imported = true
+
+	return receipt, nil
+}
+
+// receiptId keeps original ids that are UUIDs and derives one from the
+// others.
+func receiptId(originalId string) uuid.UUID {
+	if id, err := uuid.Parse(originalId); err == nil {
+		return id
+	}
+	return uuid.NewSHA1(ID_NAMESPACE, []byte(originalId))
+}
+
+// ImportCSV reads receipts with one row per item. Consecutive rows with the
+// same id are items of one receipt, whose other fields come from its first
+// row; rows without an id are receipts with a single item.
+func (im *Importer) ImportCSV(r io.Reader, mapping Mapping) (Report, error) {
+	report := Report{Rejections: []Rejection{}}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return report, nil
+	} else if err != nil {
+		return report, err
+	}
+
+	columns, err := mapping.columns(header)
+	if err != nil {
+		return report, err
+	}
+
+	var group []csvRow
+	flush := func() error {
+		if len(group) == 0 {
+			return nil
+		}
+
+		first := group[0]
+		report.Read++
+
+		raw, err := json.Marshal(receiptObject(group, columns))
+		if err != nil {
+			report.reject(first.line, first.id, err)
+		} else if err := im.importReceipt(&report, first.line, first.id, raw); err != nil {
+			return err
+		}
+
+		group = group[:0]
+		return nil
+	}
+
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return report, flush()
+		}
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			if err := flush(); err != nil {
+				return report, err
+			}
+			report.Read++
+			report.reject(parseError.StartLine, "", err)
+			continue
+		} else if err != nil {
+			return report, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := csvRow{line: line, fields: fields, id: columns.value(fields, "id")}
+
+		if len(group) > 0 && (row.id == "" || row.id != group[0].id) {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+		group = append(group, row)
+	}
+}
+
+type csvRow struct {
+	line   int
+	id     string
+	fields []string
+}
+
+// receiptObject builds the JSON object the API would receive for a group of
+// rows, leaving out empty cells.
+func receiptObject(group []csvRow, columns columnIndexes) map[string]any {
+	receipt := make(map[string]any)
+	for _, field := range RECEIPT_FIELDS {
+		if value := columns.value(group[0].fields, field); value != "" && field != "id" {
+			receipt[field] = value
+		}
+	}
+
+	items := make([]map[string]any, len(group))
+	for i, row := range group {
+		item := make(map[string]any)
+		for _, field := range ITEM_FIELDS {
+			value := columns.value(row.fields, field)
+			if value == "" {
+				continue
+			}
+
+			// Quantities are numbers in JSON. Anything else is passed on as
+			// a string so validation explains what's wrong with it.
+			item[field] = value
+			if field == "quantity" {
+				if quantity, err := strconv.Atoi(value); err == nil {
+					item[field] = quantity
+				}
+			}
+		}
+		items[i] = item
+	}
+	receipt["items"] = items
+
+	return receipt
+}
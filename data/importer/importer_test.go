@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+const legacyCSV = `Receipt No,Store,Date,Time,Item,Amount,Qty,Total
+R-1,Target,2022-01-01,13:01,Mountain Dew 12PK,6.49,,6.49
+R-2,Walgreens,2022-01-02,08:13,Pepsi - 12-oz,2.50,2,3.90
+R-2,,,,Dasani,1.40,,
+,Target,2022-13-01,13:01,Doritos,3.35,,3.35
+,Target,2022-01-01,13:01,Doritos,3.35,,4.00
+`
+
+var legacyMapping = Mapping{
+	"id":               "Receipt No",
+	"retailer":         "store",
+	"purchaseDate":     "Date",
+	"purchaseTime":     "Time",
+	"shortDescription": "Item",
+	"price":            "Amount",
+	"quantity":         "Qty",
+}
+
+func TestImportCSV(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	ruleset := entities.DefaultRuleset
+	ruleset.RetailerCharacterPoints = 10
+	importer := NewImporter(repo, &ruleset)
+
+	report, err := importer.ImportCSV(strings.NewReader(legacyCSV), legacyMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Read != 4 || report.Imported != 2 || report.Rejected != 2 {
+		t.Fatalf("Wrong report: %+v", report)
+	}
+
+	if report.Rejections[0].Record != 5 || !strings.Contains(report.Rejections[0].Reason, "purchaseDate") {
+		t.Fatalf("Wrong rejection: %+v", report.Rejections[0])
+	}
+
+	if report.Rejections[1].Record != 6 || !strings.Contains(report.Rejections[1].Reason, "total") {
+		t.Fatalf("Wrong rejection: %+v", report.Rejections[1])
+	}
+
+	// Ids that aren't UUIDs are derived from the original.
+	receipt, err := repo.ReceiptById(uuid.NewSHA1(ID_NAMESPACE, []byte("R-2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.Retailer != "Walgreens" || len(receipt.Items) != 2 || receipt.Items[0].Quantity != 2 {
+		t.Fatalf("Wrong receipt: %+v", receipt)
+	}
+
+	if receipt.Points != ruleset.CountPoints(receipt) || receipt.Points == entities.CountPoints(receipt) {
+		t.Fatalf("Receipt not scored with the import's ruleset: '%d'", receipt.Points)
+	}
+
+	// Importing the same file again doesn't duplicate receipts with ids.
+	report, err = importer.ImportCSV(strings.NewReader(legacyCSV), legacyMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Imported != 0 || report.Rejected != 4 ||
+		!strings.Contains(report.Rejections[0].Reason, "already imported") {
+		t.Fatalf("Wrong report for a repeated import: %+v", report)
+	}
+}
+
+func TestImportCSVMapping(t *testing.T) {
+	importer := NewImporter(inmemory.NewInMemoryReceiptRepository(), &entities.DefaultRuleset)
+
+	mappings := []Mapping{
+		{"store": "Store"},
+		{"retailer": "Shop"},
+		{},
+	}
+
+	for _, mapping := range mappings {
+		_, err := importer.ImportCSV(strings.NewReader(legacyCSV), mapping)
+		if !errors.Is(err, ErrInvalidMapping) {
+			t.Fatalf("Wrong error for %v: '%v' expected '%v'", mapping, err, ErrInvalidMapping)
+		}
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	importer := NewImporter(repo, &entities.DefaultRuleset)
+
+	id := uuid.New()
+	input := `{"id":"` + id.String() + `","retailer":"Target","purchaseDate":"2022-01-01",` +
+		`"purchaseTime":"13:01","items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"total":"6.49"}
+{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01","items":[],"total":"6.49"}
+{"retailer":`
+
+	report, err := importer.Import(strings.NewReader(input), JSON, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Read != 3 || report.Imported != 1 || report.Rejected != 2 {
+		t.Fatalf("Wrong report: %+v", report)
+	}
+
+	if report.Rejections[0].Record != 2 || report.Rejections[1].Record != 3 {
+		t.Fatalf("Wrong rejections: %+v", report.Rejections)
+	}
+
+	receipt, err := repo.ReceiptById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.Points != entities.CountPoints(receipt) {
+		t.Fatalf("Wrong points: '%d' expected '%d'", receipt.Points, entities.CountPoints(receipt))
+	}
+
+	if _, err := importer.Import(strings.NewReader(input), "xml", nil); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Wrong error: '%v' expected '%v'", err, ErrUnknownFormat)
+	}
+}
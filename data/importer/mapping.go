@@ -0,0 +1,112 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RECEIPT_FIELDS and ITEM_FIELDS are the receipt and item fields a CSV
+// column can hold, named as in the API. Discounts can only be imported
+// from JSON.
+var RECEIPT_FIELDS = []string{
+	"id", "retailer", "purchaseDate", "purchaseTime", "total", "currency",
+	"timezone", "subtotal", "tax", "tip", "accountId",
+}
+
+var ITEM_FIELDS = []string{
+	"shortDescription", "price", "quantity", "unitPrice", "sku", "upc",
+}
+
+// requiredFields must have a column for an import to start.
+var requiredFields = []string{
+	"retailer", "purchaseDate", "purchaseTime", "total", "shortDescription", "price",
+}
+
+// Mapping maps fields to the CSV columns holding them, by header name.
+// Fields that aren't mapped are read from the column named after them.
+type Mapping map[string]string
+
+// LoadMapping reads a mapping from a JSON object file.
+func LoadMapping(path string) (Mapping, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping Mapping
+	if err := json.Unmarshal(contents, &mapping); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMapping, err)
+	}
+
+	return mapping, nil
+}
+
+func isField(field string) bool {
+	for _, fields := range [][]string{RECEIPT_FIELDS, ITEM_FIELDS} {
+		for _, f := range fields {
+			if f == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// columnIndexes maps fields to their column's position in a row.
+type columnIndexes map[string]int
+
+// columns finds each field's column in the header. Header names are
+// compared ignoring case and surrounding spaces.
+func (m Mapping) columns(header []string) (columnIndexes, error) {
+	for field := range m {
+		if !isField(field) {
+			return nil, fmt.Errorf("%w: unknown field '%s'", ErrInvalidMapping, field)
+		}
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	columns := make(columnIndexes)
+	for _, fields := range [][]string{RECEIPT_FIELDS, ITEM_FIELDS} {
+		for _, field := range fields {
+			column, mapped := m[field]
+			if !mapped {
+				column = field
+			}
+
+			position, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+			if ok {
+				columns[field] = position
+			} else if mapped {
+				return nil, fmt.Errorf(
+					"%w: no column '%s' for field '%s'", ErrInvalidMapping, column, field,
+				)
+			}
+		}
+	}
+
+	for _, field := range requiredFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: no column for '%s'", ErrInvalidMapping, field)
+		}
+	}
+
+	return columns, nil
+}
+
+// value returns the trimmed cell holding the field, or "" if there's none.
+func (c columnIndexes) value(fields []string, field string) string {
+	position, ok := c[field]
+	if !ok || position >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[position])
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateWindow limits how far purchase dates may be from the current date.
+type DateWindow struct {
+	// AllowFuture accepts purchase dates after today.
+	AllowFuture bool
+	// MaxAgeDays rejects purchase dates more than this many days before
+	// today. Zero means no limit.
+	MaxAgeDays int
+}
+
+var DefaultDateWindow = DateWindow{AllowFuture: false, MaxAgeDays: 0}
+
+var dateWindow = DefaultDateWindow
+
+// now is replaced in tests.
+var now = time.Now
+
+// SetDateWindow changes the window purchase dates must fall in. It should be
+// called during startup, before any receipts are decoded.
+func SetDateWindow(w DateWindow) {
+	dateWindow = w
+}
+
+// storeLocation finds the time zone of receipts that don't name their own,
+// or is nil when stores have no configured time zones.
+var storeLocation func(retailer string) *time.Location
+
+// SetStoreLocations changes how receipts without a timezone field are placed
+// in a time zone when checking the window, so the check agrees with how the
+// purchase time is interpreted later. It should be called during startup.
+func SetStoreLocations(locate func(retailer string) *time.Location) {
+	storeLocation = locate
+}
+
+// Without a known time zone, "today" could be any date between the
+// furthest-behind and furthest-ahead zones.
+var earliestZone = time.FixedZone("UTC-12", -12*60*60)
+var latestZone = time.FixedZone("UTC+14", 14*60*60)
+
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// check returns an error describing why the purchase date falls outside the
+// window. location is the receipt's time zone, or nil if it isn't known.
+func (w DateWindow) check(purchaseDate time.Time, location *time.Location) error {
+	current := now()
+
+	latestToday, earliestToday := current.In(latestZone), current.In(earliestZone)
+	if location != nil {
+		latestToday, earliestToday = current.In(location), current.In(location)
+	}
+
+	if !w.AllowFuture && purchaseDate.After(calendarDate(latestToday)) {
+		return fmt.Errorf("purchaseDate is in the future")
+	}
+
+	if w.MaxAgeDays > 0 {
+		oldest := calendarDate(earliestToday).AddDate(0, 0, -w.MaxAgeDays)
+		if purchaseDate.Before(oldest) {
+			return fmt.Errorf(
+				"purchaseDate is more than %d days ago", w.MaxAgeDays,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Discount struct {
+	Description *string `json:"description"`
+	Amount      *string `json:"amount"`
+}
+
+func (d *Discount) UnmarshalJSON(b []byte) error {
+	type RawDiscount Discount
+	var parsedDiscount RawDiscount
+
+	if err := json.Unmarshal(b, &parsedDiscount); err != nil {
+		return err
+	}
+
+	// Check for missing fields
+	missingFields := make([]string, 0, 2)
+
+	if parsedDiscount.Description == nil {
+		missingFields = append(missingFields, "description")
+	}
+
+	if parsedDiscount.Amount == nil {
+		missingFields = append(missingFields, "amount")
+	}
+
+	if len(missingFields) > 0 {
+		missingFieldsList := strings.Join(missingFields, ", ")
+
+		return ReceiptError(fmt.Errorf(
+			"missing fields in at least one discount: %s",
+			missingFieldsList,
+		))
+	}
+
+	description := normalizeString(*parsedDiscount.Description)
+	parsedDiscount.Description = &description
+
+	// Check regular expressions
+	invalidFields := make([]string, 0, 2)
+
+	if !receiptStringPattern.MatchString(*parsedDiscount.Description) {
+		invalidFields = append(invalidFields, "description")
+	}
+
+	if !receiptAmountPattern.MatchString(*parsedDiscount.Amount) {
+		invalidFields = append(invalidFields, "amount")
+	}
+
+	if len(invalidFields) > 0 {
+		invalidFieldsList := strings.Join(invalidFields, ", ")
+
+		return ReceiptError(fmt.Errorf(
+			"invalid fields in at least one discount: %s",
+			invalidFieldsList,
+		))
+	}
+
+	*d = Discount(parsedDiscount)
+
+	return nil
+}
@@ -3,12 +3,54 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
 type Item struct {
 	ShortDescription *string `json:"shortDescription"`
 	Price            *string `json:"price"`
+	Quantity         *int    `json:"quantity,omitempty"`
+	UnitPrice        *string `json:"unitPrice,omitempty"`
+	SKU              *string `json:"sku,omitempty"`
+	UPC              *string `json:"upc,omitempty"`
+}
+
+// MAX_ITEM_QUANTITY bounds the number of units on a single line.
+const MAX_ITEM_QUANTITY = 9999
+
+var itemSKUPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9\-._]{0,31}$`)
+var itemUPCPattern = regexp.MustCompile(`^\d{12}$`)
+
+// validUPC reports whether upc is a UPC-A code with a correct check digit.
+func validUPC(upc string) bool {
+	if !itemUPCPattern.MatchString(upc) {
+		return false
+	}
+
+	var sum int
+	for i, c := range upc[:11] {
+		digit := int(c - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	return (10-sum%10)%10 == int(upc[11]-'0')
+}
+
+// amountThousandths converts an amount matching receiptAmountPattern into an
+// exact count of thousandths, so amounts can be compared without floats.
+func amountThousandths(amount string) int64 {
+	whole, fraction, _ := strings.Cut(amount, ".")
+	fraction = (fraction + "000")[:3]
+
+	w, _ := strconv.ParseInt(whole, 10, 64)
+	f, _ := strconv.ParseInt(fraction, 10, 64)
+
+	return w*1000 + f
 }
 
 func (i *Item) UnmarshalJSON(b []byte) error {
@@ -39,6 +81,9 @@ func (i *Item) UnmarshalJSON(b []byte) error {
 		))
 	}
 
+	shortDescription := normalizeString(*parsedItem.ShortDescription)
+	parsedItem.ShortDescription = &shortDescription
+
 	// Check regular expressions
 	invalidFields := make([]string, 0, 2)
 
@@ -46,10 +91,28 @@ func (i *Item) UnmarshalJSON(b []byte) error {
 		invalidFields = append(invalidFields, "shortDescription")
 	}
 
-	if !receiptPricePattern.MatchString(*parsedItem.Price) {
+	if !receiptAmountPattern.MatchString(*parsedItem.Price) {
 		invalidFields = append(invalidFields, "price")
 	}
 
+	if parsedItem.Quantity != nil &&
+		(*parsedItem.Quantity < 1 || *parsedItem.Quantity > MAX_ITEM_QUANTITY) {
+		invalidFields = append(invalidFields, "quantity")
+	}
+
+	if parsedItem.UnitPrice != nil &&
+		!receiptAmountPattern.MatchString(*parsedItem.UnitPrice) {
+		invalidFields = append(invalidFields, "unitPrice")
+	}
+
+	if parsedItem.SKU != nil && !itemSKUPattern.MatchString(*parsedItem.SKU) {
+		invalidFields = append(invalidFields, "sku")
+	}
+
+	if parsedItem.UPC != nil && !validUPC(*parsedItem.UPC) {
+		invalidFields = append(invalidFields, "upc")
+	}
+
 	if len(invalidFields) > 0 {
 		invalidFieldsList := strings.Join(invalidFields, ", ")
 
@@ -59,6 +122,22 @@ func (i *Item) UnmarshalJSON(b []byte) error {
 		))
 	}
 
+	// The price is the line total, so it has to be the unit price times the
+	// quantity. A missing quantity means one unit.
+	if parsedItem.UnitPrice != nil {
+		quantity := int64(1)
+		if parsedItem.Quantity != nil {
+			quantity = int64(*parsedItem.Quantity)
+		}
+
+		if quantity*amountThousandths(*parsedItem.UnitPrice) !=
+			amountThousandths(*parsedItem.Price) {
+			return ReceiptError(fmt.Errorf(
+				"price isn't quantity times unitPrice in at least one item",
+			))
+		}
+	}
+
 	*i = Item(parsedItem)
 
 	return nil
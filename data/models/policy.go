@@ -0,0 +1,64 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// StringPolicy decides which retailer names and item descriptions are
+// accepted. Strings are normalized to NFC before they are validated.
+type StringPolicy struct {
+	// AllowUnicode accepts letters, combining marks and digits from any
+	// script. Otherwise only ASCII letters and digits are accepted.
+	AllowUnicode bool
+	// Punctuation lists the characters accepted besides letters, digits,
+	// underscores and whitespace.
+	Punctuation string
+}
+
+// ASCIIStringPolicy is the original policy: ASCII word characters,
+// whitespace, hyphens and ampersands.
+var ASCIIStringPolicy = StringPolicy{
+	AllowUnicode: false,
+	Punctuation:  "-&",
+}
+
+// UnicodeStringPolicy accepts names like "Trader Joe's", "Café Rouge" and
+// "7-Eleven, Inc.".
+var UnicodeStringPolicy = StringPolicy{
+	AllowUnicode: true,
+	Punctuation:  "-&'’.,#/()!+:",
+}
+
+// Pattern returns the regular expression a normalized string must match.
+func (p StringPolicy) Pattern() *regexp.Regexp {
+	var class strings.Builder
+	if p.AllowUnicode {
+		class.WriteString(`\p{L}\p{M}\p{N}_\s`)
+	} else {
+		class.WriteString(`\w\s`)
+	}
+
+	for _, c := range p.Punctuation {
+		if strings.ContainsRune(`\[]^-`, c) {
+			class.WriteRune('\\')
+		}
+		class.WriteRune(c)
+	}
+
+	return regexp.MustCompile(`^[` + class.String() + `]+$`)
+}
+
+// SetStringPolicy changes the policy used when decoding receipts. It should
+// be called during startup, before any receipts are decoded.
+func SetStringPolicy(p StringPolicy) {
+	receiptStringPattern = p.Pattern()
+}
+
+// normalizeString returns s in NFC so visually identical strings compare and
+// count the same.
+func normalizeString(s string) string {
+	return norm.NFC.String(s)
+}
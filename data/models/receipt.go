@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/currency"
 )
 
 type Receipt struct {
@@ -13,14 +16,97 @@ type Receipt struct {
 	PurchaseDate *string `json:"purchaseDate"`
 	PurchaseTime *string `json:"purchaseTime"`
 	Total        *string `json:"total"`
+	Currency     *string `json:"currency,omitempty"`
+	Timezone     *string `json:"timezone,omitempty"`
+
+	// Subtotal, Tax, Discounts and Tip are optional. When given, the total
+	// must add up from them; see entities.ReconcileTotals.
+	Subtotal  *string     `json:"subtotal,omitempty"`
+	Tax       *string     `json:"tax,omitempty"`
+	Discounts *[]Discount `json:"discounts,omitempty"`
+	Tip       *string     `json:"tip,omitempty"`
+
+	// AccountId optionally identifies the customer the receipt belongs to,
+	// for promotions limited per account.
+	AccountId *string `json:"accountId,omitempty"`
 }
 
 type ReceiptError error
 
-var receiptStringPattern = regexp.MustCompile(`^[\w\s\-&]+$`)
+var receiptStringPattern = UnicodeStringPolicy.Pattern()
 var receiptTimePattern = regexp.MustCompile(`^[0-2]\d:[0-5]\d$`)
 var receiptDatePattern = regexp.MustCompile(`^\d{4}\-[01]\d\-[0-3]\d$`)
-var receiptPricePattern = regexp.MustCompile(`^\d{1,5}\.\d{2}$`) // Max 99999.99
+var receiptCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+var receiptAccountIdPattern = regexp.MustCompile(`^[A-Za-z0-9\-_]{1,64}$`)
+
+// receiptAmountPattern accepts an amount in any supported currency. Amounts
+// are checked against their receipt's currency with currencyPricePattern.
+var receiptAmountPattern = regexp.MustCompile(`^\d{1,7}(\.\d{1,3})?$`)
+
+// MAX_PRICE_DIGITS bounds the number of digits in an amount, so USD amounts
+// go up to 99999.99 and JPY amounts up to 9999999.
+const MAX_PRICE_DIGITS = 7
+
+// currencyPricePattern returns the pattern for amounts with the decimal
+// places defined for the currency by ISO 4217.
+func currencyPricePattern(code string) *regexp.Regexp {
+	decimals, ok := currency.MinorUnits(code)
+	if !ok {
+		return nil
+	}
+
+	if decimals == 0 {
+		return regexp.MustCompile(fmt.Sprintf(`^\d{1,%d}$`, MAX_PRICE_DIGITS))
+	}
+
+	return regexp.MustCompile(fmt.Sprintf(
+		`^\d{1,%d}\.\d{%d}$`, MAX_PRICE_DIGITS-decimals, decimals,
+	))
+}
+
+// receiptPricePatterns holds the price pattern for every supported currency,
+// keyed by ISO 4217 code.
+var receiptPricePatterns = func() map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp)
+	for _, code := range currency.Codes() {
+		patterns[code] = currencyPricePattern(code)
+	}
+	return patterns
+}()
+
+// validTimezone reports whether name is an IANA time zone such as
+// "America/Chicago".
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// FieldPatterns returns the regular expression each field is validated
+// against, keyed by JSON field name. Item fields are prefixed with "items.".
+func FieldPatterns() map[string]string {
+	return map[string]string{
+		"retailer":               receiptStringPattern.String(),
+		"purchaseDate":           receiptDatePattern.String(),
+		"purchaseTime":           receiptTimePattern.String(),
+		"total":                  receiptAmountPattern.String(),
+		"currency":               receiptCurrencyPattern.String(),
+		"items.shortDescription": receiptStringPattern.String(),
+		"items.price":            receiptAmountPattern.String(),
+		"items.unitPrice":        receiptAmountPattern.String(),
+		"items.sku":              itemSKUPattern.String(),
+		"items.upc":              itemUPCPattern.String(),
+		"subtotal":               receiptAmountPattern.String(),
+		"tax":                    receiptAmountPattern.String(),
+		"tip":                    receiptAmountPattern.String(),
+		"discounts.description":  receiptStringPattern.String(),
+		"discounts.amount":       receiptAmountPattern.String(),
+		"accountId":              receiptAccountIdPattern.String(),
+	}
+}
 
 func (r *Receipt) UnmarshalJSON(b []byte) error {
 	type RawReceipt Receipt
@@ -59,6 +145,9 @@ func (r *Receipt) UnmarshalJSON(b []byte) error {
 		return ReceiptError(fmt.Errorf("missing fields: %s", missingFieldsList))
 	}
 
+	retailer := normalizeString(*parsedReceipt.Retailer)
+	parsedReceipt.Retailer = &retailer
+
 	// Check regular expressions
 	invalidFields := make([]string, 0, 5)
 
@@ -66,24 +155,104 @@ func (r *Receipt) UnmarshalJSON(b []byte) error {
 		invalidFields = append(invalidFields, "retailer")
 	}
 
-	if !receiptDatePattern.MatchString(*parsedReceipt.PurchaseDate) {
+	// The patterns only check the shape, so also check that the date and
+	// time exist on the calendar and the clock, e.g. reject 2024-19-39 and
+	// 29:59.
+	purchaseDate, err := time.Parse("2006-01-02", *parsedReceipt.PurchaseDate)
+	if !receiptDatePattern.MatchString(*parsedReceipt.PurchaseDate) || err != nil {
 		invalidFields = append(invalidFields, "purchaseDate")
 	}
 
-	if !receiptTimePattern.MatchString(*parsedReceipt.PurchaseTime) {
+	_, err = time.Parse("15:04", *parsedReceipt.PurchaseTime)
+	if !receiptTimePattern.MatchString(*parsedReceipt.PurchaseTime) || err != nil {
 		invalidFields = append(invalidFields, "purchaseTime")
 	}
 
-	if !receiptPricePattern.MatchString(*parsedReceipt.Total) {
+	// Receipts without a currency are in the base currency.
+	currencyCode := currency.BaseCurrency()
+	if parsedReceipt.Currency != nil {
+		currencyCode = *parsedReceipt.Currency
+	}
+
+	pricePattern := receiptPricePatterns[currencyCode]
+
+	if parsedReceipt.Currency != nil && (pricePattern == nil ||
+		!receiptCurrencyPattern.MatchString(currencyCode)) {
+		invalidFields = append(invalidFields, "currency")
+	}
+
+	if pricePattern == nil || !pricePattern.MatchString(*parsedReceipt.Total) {
 		invalidFields = append(invalidFields, "total")
 	}
 
+	optionalAmounts := []struct {
+		name   string
+		amount *string
+	}{
+		{"subtotal", parsedReceipt.Subtotal},
+		{"tax", parsedReceipt.Tax},
+		{"tip", parsedReceipt.Tip},
+	}
+
+	for _, field := range optionalAmounts {
+		if field.amount != nil &&
+			(pricePattern == nil || !pricePattern.MatchString(*field.amount)) {
+			invalidFields = append(invalidFields, field.name)
+		}
+	}
+
+	if parsedReceipt.Timezone != nil && !validTimezone(*parsedReceipt.Timezone) {
+		invalidFields = append(invalidFields, "timezone")
+	}
+
+	if parsedReceipt.AccountId != nil &&
+		!receiptAccountIdPattern.MatchString(*parsedReceipt.AccountId) {
+		invalidFields = append(invalidFields, "accountId")
+	}
+
 	if len(invalidFields) > 0 {
 		invalidFieldsList := strings.Join(invalidFields, ", ")
 
 		return ReceiptError(fmt.Errorf("invalid fields: %s", invalidFieldsList))
 	}
 
+	var location *time.Location
+	if parsedReceipt.Timezone != nil {
+		location, _ = time.LoadLocation(*parsedReceipt.Timezone)
+	} else if storeLocation != nil {
+		location = storeLocation(*parsedReceipt.Retailer)
+	}
+
+	if err := dateWindow.check(purchaseDate, location); err != nil {
+		return ReceiptError(err)
+	}
+
+	// Item prices were only checked against the generic amount pattern
+	// since the currency wasn't known yet.
+	for _, item := range *parsedReceipt.Items {
+		if !pricePattern.MatchString(*item.Price) {
+			return ReceiptError(fmt.Errorf(
+				"invalid fields in at least one item: price",
+			))
+		}
+
+		if item.UnitPrice != nil && !pricePattern.MatchString(*item.UnitPrice) {
+			return ReceiptError(fmt.Errorf(
+				"invalid fields in at least one item: unitPrice",
+			))
+		}
+	}
+
+	if parsedReceipt.Discounts != nil {
+		for _, discount := range *parsedReceipt.Discounts {
+			if !pricePattern.MatchString(*discount.Amount) {
+				return ReceiptError(fmt.Errorf(
+					"invalid fields in at least one discount: amount",
+				))
+			}
+		}
+	}
+
 	*r = Receipt(parsedReceipt)
 
 	return nil
@@ -0,0 +1,364 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/currency"
+)
+
+func addFixtureSeeds(f *testing.F) {
+	_, currentFile, _, _ := runtime.Caller(0)
+	fixtures, err := filepath.Glob(filepath.Join(
+		filepath.Dir(currentFile), "..", "..", "test", "receipts", "*.json",
+	))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	// Fixtures wrap the request body alongside its expected outcome.
+	for _, fixture := range fixtures {
+		contents, err := os.ReadFile(fixture)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		var testCase struct {
+			Receipt    json.RawMessage `json:"receipt"`
+			RawReceipt *string         `json:"rawReceipt"`
+		}
+		if err := json.Unmarshal(contents, &testCase); err != nil {
+			f.Fatal(err)
+		}
+
+		if testCase.RawReceipt != nil {
+			f.Add([]byte(*testCase.RawReceipt))
+		} else {
+			f.Add([]byte(testCase.Receipt))
+		}
+	}
+
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"items":[{}]}`))
+	f.Add([]byte(`null`))
+}
+
+func FuzzReceiptUnmarshalJSON(f *testing.F) {
+	addFixtureSeeds(f)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var receipt Receipt
+		if err := json.Unmarshal(b, &receipt); err != nil {
+			return
+		}
+
+		// A successfully decoded receipt has every field, and every field
+		// matches its pattern.
+		if receipt.Items == nil || receipt.Retailer == nil ||
+			receipt.PurchaseDate == nil || receipt.PurchaseTime == nil ||
+			receipt.Total == nil {
+			t.Fatalf("Decoded receipt has missing fields: %s", b)
+		}
+
+		if !receiptStringPattern.MatchString(*receipt.Retailer) ||
+			!receiptDatePattern.MatchString(*receipt.PurchaseDate) ||
+			!receiptTimePattern.MatchString(*receipt.PurchaseTime) ||
+			!receiptAmountPattern.MatchString(*receipt.Total) {
+			t.Fatalf("Decoded receipt has invalid fields: %s", b)
+		}
+
+		for _, item := range *receipt.Items {
+			if item.ShortDescription == nil || item.Price == nil {
+				t.Fatalf("Decoded item has missing fields: %s", b)
+			}
+		}
+	})
+}
+
+func TestStringPolicy(t *testing.T) {
+	accepted := []string{
+		"Trader Joe's", "Café Rouge", "M&M's", "7-Eleven, Inc.", "Target.",
+		"Trader Joe’s", "Café", "東京ストア",
+	}
+	rejected := []string{"Target*", "<script>", "Walgreens; DROP", ""}
+
+	pattern := UnicodeStringPolicy.Pattern()
+	for _, s := range accepted {
+		if !pattern.MatchString(normalizeString(s)) {
+			t.Fatalf("Unicode policy rejected '%s'", s)
+		}
+	}
+	for _, s := range rejected {
+		if pattern.MatchString(normalizeString(s)) {
+			t.Fatalf("Unicode policy accepted '%s'", s)
+		}
+	}
+
+	if pattern := ASCIIStringPolicy.Pattern(); pattern.String() != `^[\w\s\-&]+$` {
+		t.Fatalf("Unexpected ASCII pattern: '%s'", pattern)
+	}
+
+	if ASCIIStringPolicy.Pattern().MatchString("Café") {
+		t.Fatal("ASCII policy accepted 'Café'")
+	}
+}
+
+func TestReceiptIsNormalized(t *testing.T) {
+	b := []byte(`{"retailer":"Café","purchaseDate":"2022-01-01",` +
+		`"purchaseTime":"13:01","items":[{"shortDescription":"Crème",` +
+		`"price":"1.00"}],"total":"1.00"}`)
+
+	var receipt Receipt
+	if err := json.Unmarshal(b, &receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	if *receipt.Retailer != "Café" {
+		t.Fatalf("Retailer not normalized to NFC: %q", *receipt.Retailer)
+	}
+
+	if *(*receipt.Items)[0].ShortDescription != "Crème" {
+		t.Fatalf("Description not normalized to NFC: %q", *(*receipt.Items)[0].ShortDescription)
+	}
+
+	SetStringPolicy(ASCIIStringPolicy)
+	defer SetStringPolicy(UnicodeStringPolicy)
+
+	if err := json.Unmarshal(b, &receipt); err == nil {
+		t.Fatal("ASCII policy accepted a non-ASCII retailer")
+	}
+}
+
+func TestCurrencyPrices(t *testing.T) {
+	cases := []struct {
+		currency string
+		price    string
+		valid    bool
+	}{
+		{"", "2.25", true},
+		{"", "2.250", false},
+		{"USD", "99999.99", true},
+		{"USD", "100000.00", false},
+		{"CAD", "2.25", true},
+		{"JPY", "500", true},
+		{"JPY", "500.00", false},
+		{"BHD", "1.250", true},
+		{"BHD", "1.25", false},
+		{"XYZ", "1.00", false},
+		{"usd", "1.00", false},
+	}
+
+	decode := func(code, price string) error {
+		currencyField := ""
+		if code != "" {
+			currencyField = `,"currency":"` + code + `"`
+		}
+
+		b := []byte(`{"retailer":"Target","purchaseDate":"2022-01-01",` +
+			`"purchaseTime":"13:01","items":[{"shortDescription":"Gum",` +
+			`"price":"` + price + `"}],"total":"` + price + `"` +
+			currencyField + `}`)
+
+		var receipt Receipt
+		return json.Unmarshal(b, &receipt)
+	}
+
+	for _, c := range cases {
+		if err := decode(c.currency, c.price); (err == nil) != c.valid {
+			t.Fatalf("%q %q: valid=%t, error: %v", c.currency, c.price, c.valid, err)
+		}
+	}
+
+	// Without a currency, amounts are in the base currency.
+	currency.SetBaseCurrency("JPY")
+	defer currency.SetBaseCurrency(currency.DEFAULT_CURRENCY)
+
+	if err := decode("", "500"); err != nil {
+		t.Fatalf("Amount in the JPY base rejected: %s", err)
+	}
+
+	if err := decode("", "5.00"); err == nil {
+		t.Fatal("Amount with decimals accepted in the JPY base")
+	}
+
+	if err := decode("USD", "5.00"); err != nil {
+		t.Fatalf("USD amount rejected with a JPY base: %s", err)
+	}
+}
+
+func decodeReceiptOn(date, clock, timezone string) error {
+	timezoneField := ""
+	if timezone != "" {
+		timezoneField = `,"timezone":"` + timezone + `"`
+	}
+
+	b := []byte(`{"retailer":"Target","purchaseDate":"` + date + `",` +
+		`"purchaseTime":"` + clock + `","items":[],"total":"0.00"` +
+		timezoneField + `}`)
+
+	var receipt Receipt
+	return json.Unmarshal(b, &receipt)
+}
+
+func TestCalendarValidation(t *testing.T) {
+	cases := []struct {
+		date, clock string
+		valid       bool
+	}{
+		{"2024-02-29", "23:59", true},
+		{"2023-02-29", "12:00", false},
+		{"2024-19-39", "12:00", false},
+		{"2024-04-31", "12:00", false},
+		{"2024-01-01", "29:59", false},
+		{"2024-01-01", "24:00", false},
+		{"2024-01-01", "00:00", true},
+	}
+
+	for _, c := range cases {
+		err := decodeReceiptOn(c.date, c.clock, "")
+		if (err == nil) != c.valid {
+			t.Fatalf("%s %s: valid=%t, error: %v", c.date, c.clock, c.valid, err)
+		}
+	}
+}
+
+func TestDateWindow(t *testing.T) {
+	// 2024-06-15 23:30 UTC is already 2024-06-16 in Tokyo.
+	now = func() time.Time { return time.Date(2024, 6, 15, 23, 30, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	SetDateWindow(DateWindow{AllowFuture: false, MaxAgeDays: 30})
+	defer SetDateWindow(DefaultDateWindow)
+
+	cases := []struct {
+		date, timezone string
+		valid          bool
+	}{
+		{"2024-06-15", "", true},
+		// Somewhere in the world it's already the 16th.
+		{"2024-06-16", "", true},
+		{"2024-06-17", "", false},
+		{"2024-06-16", "Asia/Tokyo", true},
+		{"2024-06-16", "America/Chicago", false},
+		{"2024-05-16", "", true},
+		{"2024-05-14", "", false},
+	}
+
+	for _, c := range cases {
+		err := decodeReceiptOn(c.date, "12:00", c.timezone)
+		if (err == nil) != c.valid {
+			t.Fatalf("%s %s: valid=%t, error: %v", c.date, c.timezone, c.valid, err)
+		}
+	}
+
+	SetDateWindow(DateWindow{AllowFuture: true})
+	if err := decodeReceiptOn("2030-01-01", "12:00", ""); err != nil {
+		t.Fatalf("Future date rejected with AllowFuture: %s", err)
+	}
+}
+
+func TestDateWindowUsesStoreLocation(t *testing.T) {
+	// 2024-06-15 23:30 UTC is still the 15th in Chicago but the 16th in Tokyo.
+	now = func() time.Time { return time.Date(2024, 6, 15, 23, 30, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	SetDateWindow(DateWindow{AllowFuture: false, MaxAgeDays: 30})
+	defer SetDateWindow(DefaultDateWindow)
+	defer SetStoreLocations(nil)
+
+	chicago, _ := time.LoadLocation("America/Chicago")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+
+	cases := []struct {
+		store          *time.Location
+		date, timezone string
+		valid          bool
+	}{
+		{chicago, "2024-06-15", "", true},
+		{chicago, "2024-06-16", "", false},
+		// The receipt's own time zone wins over the store's.
+		{chicago, "2024-06-16", "Asia/Tokyo", true},
+		{tokyo, "2024-06-16", "", true},
+		{tokyo, "2024-05-17", "", true},
+		{tokyo, "2024-05-16", "", false},
+	}
+
+	for _, c := range cases {
+		SetStoreLocations(func(string) *time.Location { return c.store })
+
+		err := decodeReceiptOn(c.date, "12:00", c.timezone)
+		if (err == nil) != c.valid {
+			t.Fatalf("%s %s in %s: valid=%t, error: %v",
+				c.date, c.timezone, c.store, c.valid, err)
+		}
+	}
+}
+
+func TestItemQuantities(t *testing.T) {
+	cases := []struct {
+		item  string
+		valid bool
+	}{
+		{`"price":"6.75","quantity":3,"unitPrice":"2.25"`, true},
+		{`"price":"6.75","quantity":3`, true},
+		{`"price":"2.25","unitPrice":"2.25"`, true},
+		{`"price":"6.70","quantity":3,"unitPrice":"2.25"`, false},
+		{`"price":"6.75","unitPrice":"2.25"`, false},
+		{`"price":"0.00","quantity":0`, false},
+		{`"price":"2.25","quantity":10000`, false},
+		{`"price":"2.25","unitPrice":"2.250"`, false},
+		{`"price":"2.25","sku":"GAT-20OZ"`, true},
+		{`"price":"2.25","sku":"-GAT"`, false},
+		{`"price":"2.25","upc":"052000328660"`, true},
+		{`"price":"2.25","upc":"052000328661"`, false},
+		{`"price":"2.25","upc":"52000328660"`, false},
+	}
+
+	for _, c := range cases {
+		b := []byte(`{"retailer":"Target","purchaseDate":"2022-01-01",` +
+			`"purchaseTime":"13:01","items":[{"shortDescription":"Gatorade",` +
+			c.item + `}],"total":"0.00"}`)
+
+		var receipt Receipt
+		err := json.Unmarshal(b, &receipt)
+		if (err == nil) != c.valid {
+			t.Fatalf("%s: valid=%t, error: %v", c.item, c.valid, err)
+		}
+	}
+}
+
+func TestOptionalFields(t *testing.T) {
+	cases := []struct {
+		fields string
+		valid  bool
+	}{
+		{`"subtotal":"2.25","tax":"0.18","tip":"1.00"`, true},
+		{`"discounts":[{"description":"Coupon","amount":"0.50"}]`, true},
+		{`"discounts":[{"description":"Coupon"}]`, false},
+		{`"discounts":[{"description":"Coupon!!$","amount":"0.50"}]`, false},
+		{`"discounts":[{"description":"Coupon","amount":"-0.50"}]`, false},
+		{`"discounts":[{"description":"Coupon","amount":"0.500"}]`, false},
+		{`"tax":"0.1"`, false},
+		{`"tip":"abc"`, false},
+		{`"subtotal":"2.250"`, false},
+		{`"accountId":"customer-1234"`, true},
+		{`"accountId":"customer 1234"`, false},
+		{`"accountId":""`, false},
+	}
+
+	for _, c := range cases {
+		b := []byte(`{"retailer":"Target","purchaseDate":"2022-01-01",` +
+			`"purchaseTime":"13:01","items":[{"shortDescription":"Gum",` +
+			`"price":"2.25"}],"total":"2.25",` + c.fields + `}`)
+
+		var receipt Receipt
+		err := json.Unmarshal(b, &receipt)
+		if (err == nil) != c.valid {
+			t.Fatalf("%s: valid=%t, error: %v", c.fields, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var ErrInvalidLayout = errors.New("invalid receipt layout")
+
+// AMOUNT matches an amount with two decimal places and optional thousands
+// separators, for use in layout patterns.
+const AMOUNT = `\d{1,3}(?:,\d{3})+\.\d{2}|\d+\.\d{2}`
+
+// HEADER_LINES is how many lines from the top a layout's header pattern is
+// looked for in.
+const HEADER_LINES = 5
+
+// Layout describes how a retailer prints its receipts. Patterns are regular
+// expressions matched against whole trimmed lines; fields left empty fall
+// back to DefaultLayout's.
+type Layout struct {
+	Name string `json:"name"`
+	// Header selects the layout for receipts with a matching line near
+	// the top.
+	Header string `json:"header"`
+	// Retailer is the retailer name to use. If empty, it's the first line.
+	Retailer string `json:"retailer,omitempty"`
+	// Currency is the ISO 4217 code of the amounts, if not the default.
+	Currency string `json:"currency,omitempty"`
+
+	// DateFormats and TimeFormats are Go time layouts, tried in order.
+	DateFormats []string `json:"dateFormats,omitempty"`
+	TimeFormats []string `json:"timeFormats,omitempty"`
+
+	// Item must capture "description" and "price" and may capture
+	// "quantity". Items with a negative price are discounts.
+	Item string `json:"item,omitempty"`
+	// Total, Subtotal, Tax and Tip must capture "amount".
+	Total    string `json:"total,omitempty"`
+	Subtotal string `json:"subtotal,omitempty"`
+	Tax      string `json:"tax,omitempty"`
+	Tip      string `json:"tip,omitempty"`
+	// Ignore lists lines to skip before the total, e.g. loyalty messages
+	// that end in an amount. Lines after the total are always skipped.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// DefaultLayout reads the common layout of a retailer name on the first
+// line, a date and time, one item per line ending in its price, and a TOTAL
+// line. Dates with slashes are read month first.
+var DefaultLayout = Layout{
+	Name:        "default",
+	DateFormats: []string{"2006-01-02", "2006/01/02", "1/2/2006", "1/2/06", "1-2-2006"},
+	TimeFormats: []string{"15:04", "15:04:05", "3:04PM", "3:04:05PM", "3:04 PM", "3:04:05 PM"},
+	Item: `^(?:(?P<quantity>\d{1,4})\s*[xX@]\s+)?(?P<description>.*?\S)\s+\$?` +
+		`(?P<price>-?(?:` + AMOUNT + `))(?P<negative>-)?(?:\s+[A-Z])?$`,
+	Total:    `(?i)^(?:GRAND\s+)?TOTAL\b[^\d]*\$?(?P<amount>` + AMOUNT + `)$`,
+	Subtotal: `(?i)^SUB\s*-?\s*TOTAL\b[^\d]*\$?(?P<amount>` + AMOUNT + `)$`,
+	Tax:      `(?i)^(?:SALES\s+)?TAX\b.*?\$?(?P<amount>` + AMOUNT + `)$`,
+	Tip:      `(?i)^(?:TIP|GRATUITY)\b[^\d]*\$?(?P<amount>` + AMOUNT + `)$`,
+}
+
+// summaryWords appear on lines that end in an amount but usually aren't
+// items.
+var summaryWords = regexp.MustCompile(
+	`(?i)\b(TOTAL|BALANCE|CHANGE|CASH|CREDIT|DEBIT|VISA|MASTERCARD|AMEX|SAVINGS|DUE|TENDER)\b`,
+)
+
+var amountPattern = regexp.MustCompile(`\d\.\d{2}\b`)
+
+type compiledLayout struct {
+	Layout
+	header   *regexp.Regexp
+	item     *regexp.Regexp
+	total    *regexp.Regexp
+	subtotal *regexp.Regexp
+	tax      *regexp.Regexp
+	tip      *regexp.Regexp
+	ignore   []*regexp.Regexp
+	// explicitDates is set when the layout gives its own date formats, so
+	// dates aren't ambiguous.
+	explicitDates bool
+}
+
+// compile fills in defaults and checks the layout's patterns.
+func (l Layout) compile() (*compiledLayout, error) {
+	if l.Name == "" {
+		return nil, fmt.Errorf("%w: missing 'name'", ErrInvalidLayout)
+	}
+
+	c := compiledLayout{Layout: l, explicitDates: len(l.DateFormats) > 0}
+
+	if len(c.DateFormats) == 0 {
+		c.DateFormats = DefaultLayout.DateFormats
+	}
+	if len(c.TimeFormats) == 0 {
+		c.TimeFormats = DefaultLayout.TimeFormats
+	}
+
+	patterns := []struct {
+		field    string
+		pattern  string
+		fallback string
+		groups   []string
+		compiled **regexp.Regexp
+	}{
+		{"header", l.Header, "", nil, &c.header},
+		{"item", l.Item, DefaultLayout.Item, []string{"description", "price"}, &c.item},
+		{"total", l.Total, DefaultLayout.Total, []string{"amount"}, &c.total},
+		{"subtotal", l.Subtotal, DefaultLayout.Subtotal, []string{"amount"}, &c.subtotal},
+		{"tax", l.Tax, DefaultLayout.Tax, []string{"amount"}, &c.tax},
+		{"tip", l.Tip, DefaultLayout.Tip, []string{"amount"}, &c.tip},
+	}
+
+	for _, p := range patterns {
+		pattern := p.pattern
+		if pattern == "" {
+			pattern = p.fallback
+		}
+		if pattern == "" {
+			continue
+		}
+
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w '%s': %s: %w", ErrInvalidLayout, l.Name, p.field, err)
+		}
+
+		for _, group := range p.groups {
+			if compiled.SubexpIndex(group) < 0 {
+				return nil, fmt.Errorf(
+					"%w '%s': %s must capture '%s'", ErrInvalidLayout, l.Name, p.field, group,
+				)
+			}
+		}
+
+		*p.compiled = compiled
+	}
+
+	for _, pattern := range l.Ignore {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w '%s': ignore: %w", ErrInvalidLayout, l.Name, err)
+		}
+		c.ignore = append(c.ignore, compiled)
+	}
+
+	return &c, nil
+}
+
+// matches reports whether the layout's header is in the first lines.
+func (c *compiledLayout) matches(lines []string) bool {
+	if c.header == nil {
+		return false
+	}
+
+	for i := 0; i < len(lines) && i < HEADER_LINES; i++ {
+		if c.header.MatchString(lines[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadParser reads retailer layouts from a JSON array file.
+func LoadParser(path string) (*Parser, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var layouts []Layout
+	if err := json.Unmarshal(contents, &layouts); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	parser := NewParser()
+	for _, layout := range layouts {
+		if err := parser.AddLayout(layout); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return parser, nil
+}
@@ -0,0 +1,379 @@
+// Package parse turns the printed text of a receipt, such as a POS journal
+// entry, into a models.Receipt. Each line is classified with a confidence so
+// callers can tell which parts were guessed.
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/models"
+)
+
+var ErrUnparseable = errors.New("unparseable receipt")
+
+type Kind string
+
+const (
+	KindRetailer     Kind = "retailer"
+	KindDateTime     Kind = "dateTime"
+	KindItem         Kind = "item"
+	KindDiscount     Kind = "discount"
+	KindSubtotal     Kind = "subtotal"
+	KindTax          Kind = "tax"
+	KindTip          Kind = "tip"
+	KindTotal        Kind = "total"
+	KindIgnored      Kind = "ignored"
+	KindUnrecognized Kind = "unrecognized"
+)
+
+// Confidence of lines that were read but could mean something else.
+const (
+	SWAPPABLE_DATE_CONFIDENCE   = 0.8
+	SUMMARY_ITEM_CONFIDENCE     = 0.5
+	UNRECOGNIZED_CONFIDENCE     = 0.5
+	REPEATED_SUMMARY_CONFIDENCE = 0.5
+)
+
+// Line is how one non-blank line of the text was read.
+type Line struct {
+	Number     int     `json:"line"`
+	Text       string  `json:"text"`
+	Kind       Kind    `json:"kind"`
+	Confidence float64 `json:"confidence"`
+	Note       string  `json:"note,omitempty"`
+}
+
+type Result struct {
+	Receipt models.Receipt `json:"receipt"`
+	Layout  string         `json:"layout"`
+	// Confidence is the lowest confidence of any line.
+	Confidence float64 `json:"confidence"`
+	Lines      []Line  `json:"lines"`
+}
+
+// Ambiguous returns the lines that were read with less than full
+// confidence.
+func (r *Result) Ambiguous() []Line {
+	ambiguous := make([]Line, 0)
+	for _, line := range r.Lines {
+		if line.Confidence < 1 {
+			ambiguous = append(ambiguous, line)
+		}
+	}
+	return ambiguous
+}
+
+// Parser picks a layout for each receipt by its header and parses it.
+type Parser struct {
+	layouts  []*compiledLayout
+	fallback *compiledLayout
+}
+
+func NewParser() *Parser {
+	fallback, err := DefaultLayout.compile()
+	if err != nil {
+		panic(err)
+	}
+
+	// The default formats guess at the order of day and month.
+	fallback.explicitDates = false
+
+	parser := Parser{fallback: fallback}
+	return &parser
+}
+
+// AddLayout adds a retailer layout. Layouts are tried in the order they're
+// added, and the default layout is used if none match.
+func (p *Parser) AddLayout(l Layout) error {
+	if l.Header == "" {
+		return fmt.Errorf("%w '%s': missing 'header'", ErrInvalidLayout, l.Name)
+	}
+
+	compiled, err := l.compile()
+	if err != nil {
+		return err
+	}
+
+	p.layouts = append(p.layouts, compiled)
+	return nil
+}
+
+func (p *Parser) layout(lines []string) *compiledLayout {
+	for _, layout := range p.layouts {
+		if layout.matches(lines) {
+			return layout
+		}
+	}
+	return p.fallback
+}
+
+// Parse reads a receipt from its text. The receipt is validated like one
+// sent as JSON; errors that aren't ErrUnparseable come from validation.
+func (p *Parser) Parse(text string) (*Result, error) {
+	numbers := make([]int, 0)
+	lines := make([]string, 0)
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			numbers = append(numbers, i+1)
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: no text", ErrUnparseable)
+	}
+
+	layout := p.layout(lines)
+	state := parseState{
+		layout:  layout,
+		result:  &Result{Layout: layout.Name, Confidence: 1},
+		amounts: make(map[Kind]string),
+	}
+
+	retailer := layout.Retailer
+	for i, text := range lines {
+		line := Line{Number: numbers[i], Text: text, Confidence: 1}
+
+		if i == 0 && retailer == "" {
+			retailer = text
+			line.Kind = KindRetailer
+		} else {
+			state.read(&line)
+		}
+
+		state.result.Lines = append(state.result.Lines, line)
+		state.result.Confidence = min(state.result.Confidence, line.Confidence)
+	}
+
+	if err := state.finish(retailer); err != nil {
+		return nil, err
+	}
+
+	return state.result, nil
+}
+
+type parseState struct {
+	layout *compiledLayout
+	result *Result
+
+	date, clock string
+	items       []map[string]any
+	discounts   []map[string]any
+	amounts     map[Kind]string
+	afterTotal  bool
+}
+
+// read classifies a line and records what it holds.
+func (s *parseState) read(line *Line) {
+	if s.afterTotal {
+		line.Kind = KindIgnored
+		return
+	}
+
+	for _, pattern := range s.layout.ignore {
+		if pattern.MatchString(line.Text) {
+			line.Kind = KindIgnored
+			return
+		}
+	}
+
+	// Summary lines come first since they would also read as items.
+	for _, kind := range []Kind{KindTotal, KindSubtotal, KindTax, KindTip} {
+		amount, ok := submatch(s.layout.summary(kind), line.Text, "amount")
+		if !ok {
+			continue
+		}
+
+		line.Kind = kind
+		if _, repeated := s.amounts[kind]; repeated {
+			line.Confidence = REPEATED_SUMMARY_CONFIDENCE
+			line.Note = fmt.Sprintf("more than one %s line; using the last", kind)
+		}
+		s.amounts[kind] = amount
+		s.afterTotal = kind == KindTotal
+		return
+	}
+
+	if s.date == "" || s.clock == "" {
+		if s.readDateTime(line) {
+			return
+		}
+	}
+
+	if match := s.layout.item.FindStringSubmatch(line.Text); match != nil {
+		s.readItem(line, match)
+		return
+	}
+
+	if amountPattern.MatchString(line.Text) {
+		line.Kind = KindUnrecognized
+		line.Confidence = UNRECOGNIZED_CONFIDENCE
+		line.Note = "has an amount but wasn't recognized"
+		return
+	}
+
+	line.Kind = KindIgnored
+}
+
+func (l *compiledLayout) summary(kind Kind) *regexp.Regexp {
+	switch kind {
+	case KindTotal:
+		return l.total
+	case KindSubtotal:
+		return l.subtotal
+	case KindTax:
+		return l.tax
+	}
+	return l.tip
+}
+
+// submatch returns a named group of the pattern's match, as an amount
+// without currency symbols or thousands separators.
+func submatch(pattern *regexp.Regexp, text, group string) (string, bool) {
+	match := pattern.FindStringSubmatch(text)
+	if match == nil {
+		return "", false
+	}
+	return cleanAmount(match[pattern.SubexpIndex(group)]), true
+}
+
+func cleanAmount(amount string) string {
+	return strings.NewReplacer(",", "", "$", "").Replace(strings.TrimSpace(amount))
+}
+
+// readDateTime looks for the purchase date and time in the line's words.
+func (s *parseState) readDateTime(line *Line) bool {
+	words := strings.Fields(line.Text)
+	found := false
+
+	for i, word := range words {
+		// Two words are tried first so "2:33 PM" isn't read as "2:33".
+		candidates := []string{word}
+		if i+1 < len(words) {
+			candidates = []string{word + " " + words[i+1], word}
+		}
+
+		for _, candidate := range candidates {
+			candidate = strings.ToUpper(candidate)
+
+			if s.date == "" {
+				if date, format, ok := parseFirst(candidate, s.layout.DateFormats); ok {
+					s.date = date.Format("2006-01-02")
+					found = true
+
+					if !s.layout.explicitDates && strings.Contains(format, "/") &&
+						!strings.HasPrefix(format, "2006") && date.Day() <= 12 && date.Day() != int(date.Month()) {
+						line.Confidence = SWAPPABLE_DATE_CONFIDENCE
+						line.Note = "read as month/day; day and month could be swapped"
+					}
+				}
+			}
+
+			if s.clock == "" {
+				if clock, _, ok := parseFirst(candidate, s.layout.TimeFormats); ok {
+					s.clock = clock.Format("15:04")
+					found = true
+				}
+			}
+		}
+	}
+
+	if found {
+		line.Kind = KindDateTime
+	}
+	return found
+}
+
+func parseFirst(value string, formats []string) (time.Time, string, bool) {
+	for _, format := range formats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, format, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+func (s *parseState) readItem(line *Line, match []string) {
+	group := func(name string) string {
+		if index := s.layout.item.SubexpIndex(name); index >= 0 {
+			return match[index]
+		}
+		return ""
+	}
+
+	description := strings.TrimSpace(group("description"))
+	price := cleanAmount(group("price"))
+	negative := strings.HasPrefix(price, "-") || group("negative") != ""
+	price = strings.TrimPrefix(price, "-")
+
+	if negative {
+		line.Kind = KindDiscount
+		s.discounts = append(s.discounts, map[string]any{
+			"description": description,
+			"amount":      price,
+		})
+		return
+	}
+
+	line.Kind = KindItem
+	item := map[string]any{"shortDescription": description, "price": price}
+	if quantity, err := strconv.Atoi(group("quantity")); err == nil && quantity > 0 {
+		item["quantity"] = quantity
+	}
+	s.items = append(s.items, item)
+
+	if summaryWords.MatchString(description) {
+		line.Confidence = SUMMARY_ITEM_CONFIDENCE
+		line.Note = "read as an item but looks like a summary line"
+	}
+}
+
+// finish builds the receipt and validates it by decoding it like a JSON
+// request.
+func (s *parseState) finish(retailer string) error {
+	switch {
+	case s.date == "":
+		return fmt.Errorf("%w: no purchase date", ErrUnparseable)
+	case s.clock == "":
+		return fmt.Errorf("%w: no purchase time", ErrUnparseable)
+	case s.amounts[KindTotal] == "":
+		return fmt.Errorf("%w: no total line", ErrUnparseable)
+	case len(s.items) == 0:
+		return fmt.Errorf("%w: no item lines", ErrUnparseable)
+	}
+
+	receipt := map[string]any{
+		"retailer":     retailer,
+		"purchaseDate": s.date,
+		"purchaseTime": s.clock,
+		"items":        s.items,
+		"total":        s.amounts[KindTotal],
+	}
+
+	for kind, field := range map[Kind]string{KindSubtotal: "subtotal", KindTax: "tax", KindTip: "tip"} {
+		if amount, ok := s.amounts[kind]; ok {
+			receipt[field] = amount
+		}
+	}
+
+	if len(s.discounts) > 0 {
+		receipt["discounts"] = s.discounts
+	}
+
+	if s.layout.Currency != "" {
+		receipt["currency"] = s.layout.Currency
+	}
+
+	encoded, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(encoded, &s.result.Receipt)
+}
@@ -0,0 +1,171 @@
+package parse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const targetText = `
+TARGET
+Minneapolis, MN
+2022-01-01 13:01
+
+Mountain Dew 12PK      6.49 T
+2 X Emils Cheese Pizza 24.50 T
+Knorr Creamy Chicken   1.26
+COUPON                 0.50-
+SUBTOTAL              31.75
+TAX                    2.54
+TOTAL                $34.29
+VISA                  34.29
+THANK YOU
+`
+
+func TestParseDefaultLayout(t *testing.T) {
+	result, err := NewParser().Parse(targetText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt := result.Receipt
+	if *receipt.Retailer != "TARGET" || *receipt.PurchaseDate != "2022-01-01" ||
+		*receipt.PurchaseTime != "13:01" || *receipt.Total != "34.29" {
+		t.Fatalf("Wrong receipt: %s %s %s %s", *receipt.Retailer, *receipt.PurchaseDate, *receipt.PurchaseTime, *receipt.Total)
+	}
+
+	items := *receipt.Items
+	if len(items) != 3 || *items[1].ShortDescription != "Emils Cheese Pizza" ||
+		*items[1].Price != "24.50" || *items[1].Quantity != 2 {
+		t.Fatalf("Wrong items: %+v", items)
+	}
+
+	if *receipt.Subtotal != "31.75" || *receipt.Tax != "2.54" ||
+		len(*receipt.Discounts) != 1 || *(*receipt.Discounts)[0].Amount != "0.50" {
+		t.Fatalf("Wrong summary amounts: %+v", receipt)
+	}
+
+	if result.Confidence != 1 || len(result.Ambiguous()) != 0 {
+		t.Fatalf("Unexpected ambiguous lines: %+v", result.Ambiguous())
+	}
+
+	// Blank lines are skipped but numbered.
+	if result.Lines[0].Number != 2 || result.Lines[len(result.Lines)-1].Kind != KindIgnored {
+		t.Fatalf("Wrong lines: %+v", result.Lines)
+	}
+}
+
+func TestParseAmbiguousLines(t *testing.T) {
+	text := `M&M Corner Market
+03/04/2022 2:33 PM
+Gatorade 2.25
+Gatorade 2.25
+Rewards balance 4.50
+Points earned: 12.00 pts
+Total 4.50`
+
+	result, err := NewParser().Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *result.Receipt.PurchaseDate != "2022-03-04" || *result.Receipt.PurchaseTime != "14:33" {
+		t.Fatalf("Wrong purchase time: %s %s", *result.Receipt.PurchaseDate, *result.Receipt.PurchaseTime)
+	}
+
+	ambiguous := result.Ambiguous()
+	if len(ambiguous) != 3 || result.Confidence != SUMMARY_ITEM_CONFIDENCE {
+		t.Fatalf("Wrong ambiguous lines: %+v", ambiguous)
+	}
+
+	expected := []struct {
+		line       int
+		kind       Kind
+		confidence float64
+	}{
+		{2, KindDateTime, SWAPPABLE_DATE_CONFIDENCE},
+		{5, KindItem, SUMMARY_ITEM_CONFIDENCE},
+		{6, KindUnrecognized, UNRECOGNIZED_CONFIDENCE},
+	}
+
+	for i, e := range expected {
+		if ambiguous[i].Number != e.line || ambiguous[i].Kind != e.kind || ambiguous[i].Confidence != e.confidence {
+			t.Fatalf("Wrong ambiguous line: %+v expected %+v", ambiguous[i], e)
+		}
+	}
+}
+
+func TestParseRetailerLayout(t *testing.T) {
+	parser := NewParser()
+	err := parser.AddLayout(Layout{
+		Name:        "corner-store",
+		Header:      `^STORE #\d+$`,
+		Retailer:    "Corner Store",
+		DateFormats: []string{"02.01.2006"},
+		Item:        `^(?P<description>.+?)\s*\.{2,}\s*(?P<price>\d+\.\d{2})$`,
+		Ignore:      []string{`^Loyalty`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := `STORE #12
+03.04.2022 09:15
+Milk........2.00
+Loyalty saved 1.00
+Bread.......3.00
+TOTAL 5.00`
+
+	result, err := parser.Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Layout != "corner-store" || *result.Receipt.Retailer != "Corner Store" ||
+		*result.Receipt.PurchaseDate != "2022-04-03" || len(*result.Receipt.Items) != 2 {
+		t.Fatalf("Wrong result: %+v", result)
+	}
+
+	// Dates in the layout's own format aren't ambiguous.
+	if result.Confidence != 1 {
+		t.Fatalf("Unexpected ambiguous lines: %+v", result.Ambiguous())
+	}
+
+	invalid := []Layout{
+		{Name: "no-header"},
+		{Name: "bad-item", Header: "X", Item: `^(?P<description>.+) (?P<amount>\d+\.\d{2})$`},
+		{Name: "bad-regexp", Header: "X", Total: `(`},
+	}
+
+	for _, layout := range invalid {
+		if err := parser.AddLayout(layout); !errors.Is(err, ErrInvalidLayout) {
+			t.Fatalf("Wrong error for '%s': '%v' expected '%v'", layout.Name, err, ErrInvalidLayout)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		text    string
+		message string
+	}{
+		{"", "no text"},
+		{"Target\nGatorade 2.25\nTOTAL 2.25", "no purchase date"},
+		{"Target\n2022-01-01\nGatorade 2.25\nTOTAL 2.25", "no purchase time"},
+		{"Target\n2022-01-01 13:01\nGatorade 2.25", "no total line"},
+		{"Target\n2022-01-01 13:01\nTOTAL 2.25", "no item lines"},
+	}
+
+	for _, c := range cases {
+		_, err := NewParser().Parse(c.text)
+		if !errors.Is(err, ErrUnparseable) || !strings.Contains(err.Error(), c.message) {
+			t.Fatalf("Wrong error for %q: '%v' expected '%s'", c.text, err, c.message)
+		}
+	}
+
+	// Parsed receipts are validated like JSON ones.
+	_, err := NewParser().Parse("Target\n2999-01-01 13:01\nGatorade 2.25\nTOTAL 2.25")
+	if err == nil || errors.Is(err, ErrUnparseable) {
+		t.Fatalf("Expected a validation error, got '%v'", err)
+	}
+}
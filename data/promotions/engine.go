@@ -0,0 +1,214 @@
+package promotions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+// Engine holds the active promotions and applies them to receipts. It is
+// safe for concurrent use.
+type Engine struct {
+	promotions map[string]*compiledPromotion
+	// redemptions counts the receipts each promotion has applied to, by
+	// promotion id then account id.
+	redemptions map[string]map[string]int
+	mutex       sync.Mutex
+}
+
+func NewEngine() *Engine {
+	return &Engine{
+		promotions:  make(map[string]*compiledPromotion),
+		redemptions: make(map[string]map[string]int),
+	}
+}
+
+// LoadEngine reads an engine's promotions from a JSON file holding an array
+// of promotions.
+func LoadEngine(path string) (*Engine, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var promotions []Promotion
+	if err := json.Unmarshal(contents, &promotions); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	engine := NewEngine()
+	for _, p := range promotions {
+		if err := engine.Add(p); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, p.Id, err)
+		}
+	}
+
+	return engine, nil
+}
+
+// Add adds a new promotion.
+func (e *Engine) Add(p Promotion) error {
+	pattern, err := p.compile()
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.promotions[p.Id]; ok {
+		return ErrAlreadyExists
+	}
+
+	e.promotions[p.Id] = &compiledPromotion{Promotion: p, itemPattern: pattern}
+	e.redemptions[p.Id] = make(map[string]int)
+
+	return nil
+}
+
+// Update replaces an existing promotion. Redemptions already counted
+// against its per-account cap are kept.
+func (e *Engine) Update(p Promotion) error {
+	pattern, err := p.compile()
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.promotions[p.Id]; !ok {
+		return ErrNotFound
+	}
+
+	e.promotions[p.Id] = &compiledPromotion{Promotion: p, itemPattern: pattern}
+
+	return nil
+}
+
+// Remove deletes a promotion and its redemption counts.
+func (e *Engine) Remove(id string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.promotions[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(e.promotions, id)
+	delete(e.redemptions, id)
+
+	return nil
+}
+
+// Promotion returns the promotion with the given id.
+func (e *Engine) Promotion(id string) (Promotion, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	p, ok := e.promotions[id]
+	if !ok {
+		return Promotion{}, ErrNotFound
+	}
+
+	return p.Promotion, nil
+}
+
+// Promotions returns every promotion, ordered by id.
+func (e *Engine) Promotions() []Promotion {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	promotions := make([]Promotion, 0, len(e.promotions))
+	for _, p := range e.promotions {
+		promotions = append(promotions, p.Promotion)
+	}
+
+	sort.Slice(promotions, func(i, j int) bool {
+		return promotions[i].Id < promotions[j].Id
+	})
+
+	return promotions
+}
+
+// Apply adds the points from every promotion the receipt qualifies for to
+// its Points, which should hold the points from entities.CountPoints, and
+// records them in its Promotions. Redemptions are counted against
+// per-account caps; call Release if the receipt isn't kept.
+//
+// Promotions are considered from the highest priority down, by id within a
+// priority. The first that matches always applies. If it isn't stackable,
+// it's the only one; otherwise every other matching stackable promotion
+// applies too. Multipliers each add their share of the rules' points, so 2x
+// and 3x promotions together give 4x rather than 6x.
+func (e *Engine) Apply(r *entities.Receipt) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	candidates := make([]*compiledPromotion, 0, len(e.promotions))
+	for _, p := range e.promotions {
+		if e.applies(p, r) {
+			candidates = append(candidates, p)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority > candidates[j].Priority
+		}
+		return candidates[i].Id < candidates[j].Id
+	})
+
+	basePoints := r.Points
+	r.Promotions = nil
+
+	for _, p := range candidates {
+		if len(r.Promotions) > 0 && !p.Stackable {
+			continue
+		}
+
+		applied := p.points(basePoints)
+		r.Points += applied.Points
+		r.Promotions = append(r.Promotions, applied)
+
+		if p.MaxPerAccount > 0 {
+			e.redemptions[p.Id][r.AccountId]++
+		}
+
+		if !p.Stackable {
+			break
+		}
+	}
+}
+
+// applies reports whether the promotion matches the receipt and its account
+// is under the cap. The caller must hold the lock.
+func (e *Engine) applies(p *compiledPromotion, r *entities.Receipt) bool {
+	if !p.matches(r) {
+		return false
+	}
+
+	return p.MaxPerAccount == 0 || e.redemptions[p.Id][r.AccountId] < p.MaxPerAccount
+}
+
+// Release undoes the redemptions Apply counted for a receipt that wasn't
+// kept, e.g. because storing it failed.
+func (e *Engine) Release(r *entities.Receipt) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, applied := range r.Promotions {
+		p, ok := e.promotions[applied.PromotionId]
+		if !ok || p.MaxPerAccount == 0 {
+			continue
+		}
+
+		if e.redemptions[p.Id][r.AccountId] > 0 {
+			e.redemptions[p.Id][r.AccountId]--
+		}
+	}
+}
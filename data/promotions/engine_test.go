@@ -0,0 +1,188 @@
+package promotions
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+var weekendStarts = time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+var weekendEnds = time.Date(2024, 6, 17, 0, 0, 0, 0, time.UTC)
+
+func makeReceipt(retailerId, accountId string, purchased time.Time, items ...string) *entities.Receipt {
+	receipt := &entities.Receipt{
+		RetailerId:       retailerId,
+		AccountId:        accountId,
+		PurchaseDateTime: purchased,
+		Points:           50,
+	}
+
+	for _, description := range items {
+		receipt.Items = append(receipt.Items, entities.Item{ShortDescription: description})
+	}
+
+	return receipt
+}
+
+func addPromotions(t *testing.T, e *Engine, promotions ...Promotion) {
+	for _, p := range promotions {
+		if err := e.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPromotionScope(t *testing.T) {
+	engine := NewEngine()
+	addPromotions(t, engine,
+		Promotion{
+			Id: "target-weekend", Name: "2x points at Target", RetailerId: "target",
+			Starts: weekendStarts, Ends: weekendEnds, Multiplier: 2, Stackable: true,
+		},
+		Promotion{
+			Id: "gatorade", Name: "+100 points for Gatorade", ItemPattern: `\bgatorade\b`,
+			Starts: weekendStarts, Ends: weekendEnds, Bonus: 100, Stackable: true,
+		},
+	)
+
+	saturday := weekendStarts.Add(12 * time.Hour)
+
+	cases := []struct {
+		description string
+		receipt     *entities.Receipt
+		points      int
+	}{
+		{"neither", makeReceipt("walgreens", "", saturday, "Doritos"), 50},
+		{"retailer", makeReceipt("target", "", saturday, "Doritos"), 100},
+		{"item", makeReceipt("walgreens", "", saturday, "GATORADE Cool Blue"), 150},
+		{"both", makeReceipt("target", "", saturday, "Gatorade"), 200},
+		{"before", makeReceipt("target", "", weekendStarts.Add(-time.Minute), "Gatorade"), 50},
+		{"at end", makeReceipt("target", "", weekendEnds, "Gatorade"), 50},
+	}
+
+	for _, c := range cases {
+		engine.Apply(c.receipt)
+		if c.receipt.Points != c.points {
+			t.Fatalf("Wrong points for %s: '%d' expected '%d'", c.description, c.receipt.Points, c.points)
+		}
+	}
+}
+
+func TestPromotionStacking(t *testing.T) {
+	engine := NewEngine()
+	addPromotions(t, engine,
+		Promotion{
+			Id: "double", Name: "2x", Starts: weekendStarts, Ends: weekendEnds,
+			Multiplier: 2, Priority: 1, Stackable: true,
+		},
+		Promotion{
+			Id: "triple", Name: "3x", Starts: weekendStarts, Ends: weekendEnds,
+			Multiplier: 3, Priority: 1, Stackable: true,
+		},
+		Promotion{
+			Id: "exclusive", Name: "+10", Starts: weekendStarts, Ends: weekendEnds,
+			Bonus: 10, Priority: 0,
+		},
+	)
+
+	receipt := makeReceipt("target", "", weekendStarts)
+	engine.Apply(receipt)
+
+	// 2x and 3x add 50 and 100 points; the lower priority exclusive
+	// promotion can't join them.
+	if receipt.Points != 200 || len(receipt.Promotions) != 2 {
+		t.Fatalf("Wrong stacked points: '%d' expected '%d'", receipt.Points, 200)
+	}
+
+	exclusive, _ := engine.Promotion("exclusive")
+	exclusive.Priority = 2
+	if err := engine.Update(exclusive); err != nil {
+		t.Fatal(err)
+	}
+
+	receipt = makeReceipt("target", "", weekendStarts)
+	engine.Apply(receipt)
+
+	if receipt.Points != 60 || len(receipt.Promotions) != 1 {
+		t.Fatalf("Wrong exclusive points: '%d' expected '%d'", receipt.Points, 60)
+	}
+}
+
+func TestPromotionCaps(t *testing.T) {
+	engine := NewEngine()
+	addPromotions(t, engine, Promotion{
+		Id: "first-two", Name: "+100 twice", Starts: weekendStarts, Ends: weekendEnds,
+		Bonus: 100, MaxPerAccount: 2,
+	})
+
+	expected := []int{150, 150, 50}
+	for i, points := range expected {
+		receipt := makeReceipt("target", "alice", weekendStarts)
+		engine.Apply(receipt)
+		if receipt.Points != points {
+			t.Fatalf("Wrong points for receipt %d: '%d' expected '%d'", i, receipt.Points, points)
+		}
+	}
+
+	other := makeReceipt("target", "bob", weekendStarts)
+	engine.Apply(other)
+	if other.Points != 150 {
+		t.Fatalf("Wrong points for another account: '%d' expected '%d'", other.Points, 150)
+	}
+
+	// A receipt that wasn't stored gives its redemption back.
+	engine.Release(other)
+	retried := makeReceipt("target", "bob", weekendStarts)
+	engine.Apply(retried)
+	engine.Apply(makeReceipt("target", "bob", weekendStarts))
+	if retried.Points != 150 {
+		t.Fatalf("Wrong points after release: '%d' expected '%d'", retried.Points, 150)
+	}
+
+	anonymous := makeReceipt("target", "", weekendStarts)
+	engine.Apply(anonymous)
+	if anonymous.Points != 50 {
+		t.Fatalf("Capped promotion applied without an account: '%d'", anonymous.Points)
+	}
+}
+
+func TestPromotionValidation(t *testing.T) {
+	valid := Promotion{
+		Id: "valid", Name: "Valid", Starts: weekendStarts, Ends: weekendEnds, Bonus: 10,
+	}
+
+	invalid := []func(p *Promotion){
+		func(p *Promotion) { p.Id = "Not Valid" },
+		func(p *Promotion) { p.Name = "" },
+		func(p *Promotion) { p.Ends = p.Starts },
+		func(p *Promotion) { p.Starts = time.Time{} },
+		func(p *Promotion) { p.Bonus = 0 },
+		func(p *Promotion) { p.Bonus = -5 },
+		func(p *Promotion) { p.Multiplier = 0.5 },
+		func(p *Promotion) { p.Multiplier = 200 },
+		func(p *Promotion) { p.ItemPattern = "(" },
+		func(p *Promotion) { p.RetailerId = "Target Store" },
+		func(p *Promotion) { p.MaxPerAccount = -1 },
+	}
+
+	engine := NewEngine()
+	for i, change := range invalid {
+		p := valid
+		change(&p)
+		if err := engine.Add(p); !errors.Is(err, ErrInvalidPromotion) {
+			t.Fatalf("Wrong error for invalid promotion %d: %v", i, err)
+		}
+	}
+
+	addPromotions(t, engine, valid)
+
+	if err := engine.Add(valid); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Wrong error for a duplicate promotion: %v", err)
+	}
+
+	if err := engine.Remove("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Wrong error for a missing promotion: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package promotions
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+var ErrNotFound = errors.New("promotion not found")
+var ErrAlreadyExists = errors.New("promotion already exists")
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+// MAX_MULTIPLIER and MAX_BONUS bound what a single promotion can award, to
+// catch typos like a 200x multiplier.
+const MAX_MULTIPLIER = 10.0
+const MAX_BONUS = 100000
+
+const MAX_ITEM_PATTERN_LENGTH = 256
+
+var promotionIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9\-]{0,63}$`)
+var retailerIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9\-]{0,63}$`)
+
+// Promotion awards extra points to receipts in its scope, e.g. "2x points at
+// Target this weekend" or "+100 points for buying Gatorade".
+type Promotion struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+
+	// RetailerId limits the promotion to a canonical retailer. Empty means
+	// every retailer.
+	RetailerId string `json:"retailerId,omitempty"`
+	// ItemPattern limits the promotion to receipts with at least one item
+	// whose description matches this case-insensitive regular expression.
+	// Empty means every receipt.
+	ItemPattern string `json:"itemPattern,omitempty"`
+	// Starts and Ends bound when the purchase must have happened. Ends is
+	// exclusive.
+	Starts time.Time `json:"starts"`
+	Ends   time.Time `json:"ends"`
+
+	// Multiplier scales the points from the rules, e.g. 2 doubles them.
+	// Zero means no multiplier. Bonus is a flat number of points.
+	Multiplier float64 `json:"multiplier,omitempty"`
+	Bonus      int     `json:"bonus,omitempty"`
+
+	// Promotions are considered from the highest priority down. Stackable
+	// promotions combine with each other; a promotion that isn't stackable
+	// only applies on its own.
+	Priority  int  `json:"priority"`
+	Stackable bool `json:"stackable"`
+
+	// MaxPerAccount limits how many receipts from one account the promotion
+	// applies to. Zero means no limit. Capped promotions don't apply to
+	// receipts without an account.
+	MaxPerAccount int `json:"maxPerAccount,omitempty"`
+}
+
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPromotion, reason)
+}
+
+// compile validates the promotion and compiles its item pattern.
+func (p *Promotion) compile() (*regexp.Regexp, error) {
+	switch {
+	case !promotionIdPattern.MatchString(p.Id):
+		return nil, invalid("id must be lowercase letters, digits and dashes")
+	case p.Name == "":
+		return nil, invalid("name is required")
+	case p.RetailerId != "" && !retailerIdPattern.MatchString(p.RetailerId):
+		return nil, invalid("retailerId isn't a valid retailer id")
+	case p.Starts.IsZero() || p.Ends.IsZero():
+		return nil, invalid("starts and ends are required")
+	case !p.Ends.After(p.Starts):
+		return nil, invalid("ends must be after starts")
+	case p.Multiplier == 0 && p.Bonus == 0:
+		return nil, invalid("a multiplier or bonus is required")
+	case p.Multiplier != 0 && (p.Multiplier <= 1 || p.Multiplier > MAX_MULTIPLIER):
+		return nil, invalid(fmt.Sprintf("multiplier must be above 1 and at most %g", MAX_MULTIPLIER))
+	case p.Bonus < 0 || p.Bonus > MAX_BONUS:
+		return nil, invalid(fmt.Sprintf("bonus must be between 0 and %d", MAX_BONUS))
+	case p.MaxPerAccount < 0:
+		return nil, invalid("maxPerAccount can't be negative")
+	case len(p.ItemPattern) > MAX_ITEM_PATTERN_LENGTH:
+		return nil, invalid(fmt.Sprintf("itemPattern is longer than %d characters", MAX_ITEM_PATTERN_LENGTH))
+	}
+
+	if p.ItemPattern == "" {
+		return nil, nil
+	}
+
+	pattern, err := regexp.Compile("(?i)" + p.ItemPattern)
+	if err != nil {
+		return nil, invalid("itemPattern isn't a valid regular expression")
+	}
+
+	return pattern, nil
+}
+
+// compiledPromotion is a validated promotion with its item pattern compiled.
+type compiledPromotion struct {
+	Promotion
+	itemPattern *regexp.Regexp
+}
+
+// matches reports whether the receipt is in the promotion's scope, ignoring
+// per-account caps.
+func (p *compiledPromotion) matches(r *entities.Receipt) bool {
+	if p.RetailerId != "" && p.RetailerId != r.RetailerId {
+		return false
+	}
+
+	if r.PurchaseDateTime.Before(p.Starts) || !r.PurchaseDateTime.Before(p.Ends) {
+		return false
+	}
+
+	if p.MaxPerAccount > 0 && r.AccountId == "" {
+		return false
+	}
+
+	if p.itemPattern == nil {
+		return true
+	}
+
+	for _, item := range r.Items {
+		if p.itemPattern.MatchString(item.ShortDescription) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// points returns the extra points the promotion awards on top of the points
+// from the rules, with the terms they were worked out from.
+func (p *compiledPromotion) points(basePoints int) entities.PromotionPoints {
+	applied := entities.PromotionPoints{
+		PromotionId: p.Id,
+		Multiplier:  p.Multiplier,
+		Bonus:       p.Bonus,
+	}
+	applied.Points = applied.PointsFor(basePoints)
+	return applied
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import "errors"
+
+// Implementations wrap these errors with context, so check for them with
+// errors.Is.
+var (
+	ErrNotFound      = errors.New("receipt not found")
+	ErrAlreadyExists = errors.New("receipt already exists")
+	ErrCapacity      = errors.New("repository is at capacity")
+	ErrUnavailable   = errors.New("repository is unavailable")
+)
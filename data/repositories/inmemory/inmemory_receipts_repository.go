@@ -4,19 +4,37 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/repositories"
 )
 
 type InMemoryReceiptRepository struct {
-	receipts map[uuid.UUID]*entities.Receipt
-	mutex    sync.RWMutex
+	receipts     map[uuid.UUID]*entities.Receipt
+	order        []uuid.UUID
+	positions    map[uuid.UUID]int
+	capacity     int
+	outbox       []events.Event
+	outboxOn     bool
+	lastSequence uint64
+	mutex        sync.RWMutex
 }
 
 func NewInMemoryReceiptRepository() *InMemoryReceiptRepository {
+	return NewBoundedInMemoryReceiptRepository(0)
+}
+
+// NewBoundedInMemoryReceiptRepository creates a repository that rejects new
+// receipts once it holds capacity of them. A capacity of 0 means unbounded.
+func NewBoundedInMemoryReceiptRepository(capacity int) *InMemoryReceiptRepository {
 	inMemoryRepo := InMemoryReceiptRepository{
-		receipts: make(map[uuid.UUID]*entities.Receipt),
+		receipts:  make(map[uuid.UUID]*entities.Receipt),
+		positions: make(map[uuid.UUID]int),
+		capacity:  capacity,
+		outbox:    make([]events.Event, 0),
 	}
 	return &inMemoryRepo
 }
@@ -27,7 +45,7 @@ func (r *InMemoryReceiptRepository) ReceiptById(id uuid.UUID) (*entities.Receipt
 
 	receipt, ok := r.receipts[id]
 	if !ok {
-		return nil, fmt.Errorf("No receipt with ID \"%s\"", id)
+		return nil, fmt.Errorf("%w: no receipt with ID \"%s\"", repositories.ErrNotFound, id)
 	}
 
 	log.Printf("Receipt with ID '%s' retrieved\n", receipt.Id)
@@ -36,22 +54,142 @@ func (r *InMemoryReceiptRepository) ReceiptById(id uuid.UUID) (*entities.Receipt
 }
 
 func (r *InMemoryReceiptRepository) AddReceipt(receipt *entities.Receipt) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.receipts[receipt.Id]; ok {
+		return fmt.Errorf(
+			"%w: receipt with ID \"%s\"", repositories.ErrAlreadyExists, receipt.Id,
+		)
+	}
+
+	if r.capacity > 0 && len(r.receipts) >= r.capacity {
+		return fmt.Errorf(
+			"%w: %d receipts stored", repositories.ErrCapacity, len(r.receipts),
+		)
+	}
+
+	r.receipts[receipt.Id] = receipt
+	r.positions[receipt.Id] = len(r.order)
+	r.order = append(r.order, receipt.Id)
+	eventType := events.ReceiptAdded
+	if receipt.Imported {
+		eventType = events.ReceiptImported
+	}
+	r.appendEvent(eventType, receipt, receipt.Points)
+
+	log.Printf("Receipt with ID '%s' saved\n", receipt.Id)
+
+	return nil
+}
+
+func (r *InMemoryReceiptRepository) ListReceipts(
+	filter repositories.ReceiptFilter, after uuid.UUID, limit int,
+) ([]*entities.Receipt, error) {
 	r.mutex.RLock()
-	_, ok := r.receipts[receipt.Id]
+	defer r.mutex.RUnlock()
 
-	if ok {
-		r.mutex.RUnlock()
-		return fmt.Errorf("Receipt already exists with ID \"%s\"", receipt.Id)
+	start := 0
+	if after != uuid.Nil {
+		position, ok := r.positions[after]
+		if !ok {
+			return nil, fmt.Errorf("%w: no receipt with ID \"%s\"", repositories.ErrNotFound, after)
+		}
+		start = position + 1
 	}
 
-	r.mutex.RUnlock()
+	if limit <= 0 {
+		return []*entities.Receipt{}, nil
+	}
+
+	receipts := make([]*entities.Receipt, 0, min(limit, len(r.order)-start))
+	for _, id := range r.order[start:] {
+		if len(receipts) >= limit {
+			break
+		}
+
+		receipt := r.receipts[id]
+		if !filter.Matches(receipt) {
+			continue
+		}
 
-	// TODO: mitigate potential DoS if too many read locks are held
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, nil
+}
+
+func (r *InMemoryReceiptRepository) UpdatePoints(id uuid.UUID, points int) (int, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	r.receipts[receipt.Id] = receipt
 
-	log.Printf("Receipt with ID '%s' saved\n", receipt.Id)
+	receipt, ok := r.receipts[id]
+	if !ok {
+		return 0, fmt.Errorf("%w: no receipt with ID \"%s\"", repositories.ErrNotFound, id)
+	}
+
+	// Replace the receipt rather than changing it, since callers of
+	// ReceiptById may be reading it without the lock.
+	updated := *receipt
+	updated.Points = points
+	r.receipts[id] = &updated
+	r.appendEvent(events.ReceiptRescored, &updated, receipt.Points)
+
+	log.Printf("Receipt with ID '%s' rescored\n", id)
+
+	return receipt.Points, nil
+}
+
+// EnableOutbox starts recording events for an events.Relay to publish.
+// Events are only removed once published, so repositories without a relay
+// draining them leave the outbox off.
+func (r *InMemoryReceiptRepository) EnableOutbox() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.outboxOn = true
+}
+
+// appendEvent records an event in the outbox, if it's enabled. Callers must
+// hold the write lock so the event is stored atomically with the change it
+// describes.
+func (r *InMemoryReceiptRepository) appendEvent(
+	t events.Type, receipt *entities.Receipt, previousPoints int,
+) {
+	if !r.outboxOn {
+		return
+	}
+
+	r.lastSequence++
+	r.outbox = append(r.outbox, events.Event{
+		Sequence:       r.lastSequence,
+		Type:           t,
+		Receipt:        *receipt,
+		PreviousPoints: previousPoints,
+		OccurredAt:     time.Now(),
+	})
+}
+
+func (r *InMemoryReceiptRepository) PendingEvents(limit int) ([]events.Event, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	n := min(limit, len(r.outbox))
+	pending := make([]events.Event, n)
+	copy(pending, r.outbox[:n])
+
+	return pending, nil
+}
+
+func (r *InMemoryReceiptRepository) MarkPublished(sequence uint64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := 0
+	for i < len(r.outbox) && r.outbox[i].Sequence <= sequence {
+		i++
+	}
+	r.outbox = r.outbox[i:]
 
 	return nil
 }
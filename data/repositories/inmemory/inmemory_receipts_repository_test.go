@@ -0,0 +1,77 @@
+package inmemory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+	"github.com/vimolicious/receipt-processor/data/repositories/repotest"
+)
+
+func TestInMemoryReceiptRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repositories.ReceiptRepository {
+		return NewInMemoryReceiptRepository()
+	})
+}
+
+func TestBoundedInMemoryReceiptRepository(t *testing.T) {
+	repo := NewBoundedInMemoryReceiptRepository(1)
+
+	if err := repo.AddReceipt(&entities.Receipt{Id: uuid.New()}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := repo.AddReceipt(&entities.Receipt{Id: uuid.New()})
+	if !errors.Is(err, repositories.ErrCapacity) {
+		t.Fatalf("AddReceipt returned '%v' when full, expected ErrCapacity", err)
+	}
+}
+
+func TestUpdatePointsEvent(t *testing.T) {
+	repo := NewInMemoryReceiptRepository()
+	repo.EnableOutbox()
+	receipt := &entities.Receipt{Id: uuid.New(), Points: 28}
+
+	if err := repo.AddReceipt(receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.UpdatePoints(receipt.Id, 48); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := repo.PendingEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 2 || pending[1].Type != events.ReceiptRescored ||
+		pending[1].PreviousPoints != 28 || pending[1].Receipt.Points != 48 {
+		t.Fatalf("Wrong events after UpdatePoints: %+v", pending)
+	}
+
+	// The stored receipt was replaced, not changed under earlier readers.
+	if receipt.Points != 28 {
+		t.Fatalf("Original receipt changed: '%d' expected '%d'", receipt.Points, 28)
+	}
+}
+
+func TestOutboxIsOptIn(t *testing.T) {
+	repo := NewInMemoryReceiptRepository()
+
+	if err := repo.AddReceipt(&entities.Receipt{Id: uuid.New()}); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := repo.PendingEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 0 {
+		t.Fatalf("Events recorded without an outbox: %+v", pending)
+	}
+}
@@ -1,6 +1,9 @@
 package repositories
 
 import (
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/vimolicious/receipt-processor/data/entities"
 )
@@ -8,4 +11,40 @@ import (
 type ReceiptRepository interface {
 	ReceiptById(uuid.UUID) (*entities.Receipt, error)
 	AddReceipt(*entities.Receipt) error
+	// ListReceipts returns up to limit receipts matching the filter, in the
+	// order they were added, starting after the receipt with ID after. Pass
+	// uuid.Nil to start from the first receipt, and the last receipt of a
+	// page to get the next one.
+	ListReceipts(filter ReceiptFilter, after uuid.UUID, limit int) ([]*entities.Receipt, error)
+	// UpdatePoints changes a stored receipt's points and returns the points
+	// it had before.
+	UpdatePoints(id uuid.UUID, points int) (int, error)
+}
+
+// ReceiptFilter selects stored receipts. Zero fields match every receipt.
+type ReceiptFilter struct {
+	// RetailerId matches the canonical retailer id exactly.
+	RetailerId string `json:"retailerId,omitempty"`
+	// Retailer matches the retailer name as written, ignoring case.
+	Retailer string `json:"retailer,omitempty"`
+	// PurchasedFrom and PurchasedTo bound the purchase time. PurchasedTo is
+	// exclusive.
+	PurchasedFrom time.Time `json:"purchasedFrom"`
+	PurchasedTo   time.Time `json:"purchasedTo"`
+}
+
+// Matches reports whether the receipt is selected by the filter.
+func (f *ReceiptFilter) Matches(r *entities.Receipt) bool {
+	switch {
+	case f.RetailerId != "" && f.RetailerId != r.RetailerId:
+		return false
+	case f.Retailer != "" && !strings.EqualFold(f.Retailer, r.Retailer):
+		return false
+	case !f.PurchasedFrom.IsZero() && r.PurchaseDateTime.Before(f.PurchasedFrom):
+		return false
+	case !f.PurchasedTo.IsZero() && !r.PurchaseDateTime.Before(f.PurchasedTo):
+		return false
+	}
+
+	return true
 }
@@ -0,0 +1,273 @@
+// Package repotest provides a conformance suite that every
+// repositories.ReceiptRepository implementation should pass.
+package repotest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+)
+
+// Factory returns a new, empty repository for each subtest.
+type Factory func(t *testing.T) repositories.ReceiptRepository
+
+func newReceipt(retailer string, points int) *entities.Receipt {
+	return &entities.Receipt{
+		Id:       uuid.New(),
+		Retailer: retailer,
+		Items: []entities.Item{
+			{ShortDescription: "Gatorade", Price: 2.25},
+			{ShortDescription: "Doritos", Price: 3.35},
+		},
+		PurchaseDateTime: time.Date(2022, 3, 20, 14, 33, 0, 0, time.UTC),
+		Total:            5.60,
+		Points:           points,
+	}
+}
+
+// Run runs the conformance suite against repositories created by factory.
+func Run(t *testing.T, factory Factory) {
+	t.Run("AddAndGet", func(t *testing.T) { testAddAndGet(t, factory(t)) })
+	t.Run("DuplicateId", func(t *testing.T) { testDuplicateId(t, factory(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, factory(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, factory(t)) })
+	t.Run("UpdatePoints", func(t *testing.T) { testUpdatePoints(t, factory(t)) })
+	t.Run("ConcurrentWriters", func(t *testing.T) { testConcurrentWriters(t, factory(t)) })
+	t.Run("ConcurrentDuplicates", func(t *testing.T) { testConcurrentDuplicates(t, factory(t)) })
+}
+
+func testAddAndGet(t *testing.T, repo repositories.ReceiptRepository) {
+	receipt := newReceipt("Target", 28)
+
+	if err := repo.AddReceipt(receipt); err != nil {
+		t.Fatalf("AddReceipt: %s", err)
+	}
+
+	got, err := repo.ReceiptById(receipt.Id)
+	if err != nil {
+		t.Fatalf("ReceiptById: %s", err)
+	}
+
+	if got.Id != receipt.Id || got.Retailer != receipt.Retailer ||
+		got.Points != receipt.Points || got.Total != receipt.Total ||
+		!got.PurchaseDateTime.Equal(receipt.PurchaseDateTime) ||
+		len(got.Items) != len(receipt.Items) {
+		t.Fatalf("ReceiptById returned %+v, expected %+v", got, receipt)
+	}
+}
+
+func testDuplicateId(t *testing.T, repo repositories.ReceiptRepository) {
+	receipt := newReceipt("Target", 28)
+
+	if err := repo.AddReceipt(receipt); err != nil {
+		t.Fatalf("AddReceipt: %s", err)
+	}
+
+	duplicate := newReceipt("Walgreens", 10)
+	duplicate.Id = receipt.Id
+
+	if err := repo.AddReceipt(duplicate); !errors.Is(err, repositories.ErrAlreadyExists) {
+		t.Fatalf("AddReceipt returned '%v' for a duplicate ID, expected ErrAlreadyExists", err)
+	}
+
+	got, err := repo.ReceiptById(receipt.Id)
+	if err != nil {
+		t.Fatalf("ReceiptById: %s", err)
+	}
+
+	if got.Retailer != "Target" {
+		t.Fatalf("Duplicate overwrote the original receipt: %+v", got)
+	}
+}
+
+func testNotFound(t *testing.T, repo repositories.ReceiptRepository) {
+	if _, err := repo.ReceiptById(uuid.New()); !errors.Is(err, repositories.ErrNotFound) {
+		t.Fatalf("ReceiptById returned '%v' for an unknown ID, expected ErrNotFound", err)
+	}
+}
+
+func testList(t *testing.T, repo repositories.ReceiptRepository) {
+	retailers := []string{"Target", "Walgreens", "TARGET", "Target", "Walgreens"}
+
+	added := make([]*entities.Receipt, len(retailers))
+	for i, retailer := range retailers {
+		added[i] = newReceipt(retailer, i)
+		added[i].PurchaseDateTime = added[i].PurchaseDateTime.AddDate(0, 0, i)
+		if err := repo.AddReceipt(added[i]); err != nil {
+			t.Fatalf("AddReceipt: %s", err)
+		}
+	}
+
+	// after is the index of the receipt a page starts after, or -1 to start
+	// from the first receipt.
+	cases := []struct {
+		description string
+		filter      repositories.ReceiptFilter
+		after       int
+		limit       int
+		expected    []int
+	}{
+		{"everything", repositories.ReceiptFilter{}, -1, 10, []int{0, 1, 2, 3, 4}},
+		{"first page", repositories.ReceiptFilter{}, -1, 2, []int{0, 1}},
+		{"next page", repositories.ReceiptFilter{}, 1, 2, []int{2, 3}},
+		{"last page", repositories.ReceiptFilter{}, 3, 2, []int{4}},
+		{"past the end", repositories.ReceiptFilter{}, 4, 2, []int{}},
+		{"no limit", repositories.ReceiptFilter{}, -1, 0, []int{}},
+		{"retailer", repositories.ReceiptFilter{Retailer: "target"}, -1, 10, []int{0, 2, 3}},
+		{"retailer page", repositories.ReceiptFilter{Retailer: "target"}, 0, 1, []int{2}},
+		{"retailer after a mismatch", repositories.ReceiptFilter{Retailer: "target"}, 1, 10, []int{2, 3}},
+		{
+			"purchase dates",
+			repositories.ReceiptFilter{
+				PurchasedFrom: added[1].PurchaseDateTime,
+				PurchasedTo:   added[3].PurchaseDateTime,
+			},
+			-1, 10, []int{1, 2},
+		},
+	}
+
+	for _, c := range cases {
+		after := uuid.Nil
+		if c.after >= 0 {
+			after = added[c.after].Id
+		}
+
+		listed, err := repo.ListReceipts(c.filter, after, c.limit)
+		if err != nil {
+			t.Fatalf("ListReceipts: %s", err)
+		}
+
+		if len(listed) != len(c.expected) {
+			t.Fatalf("%s: ListReceipts returned %d receipts, expected %d", c.description, len(listed), len(c.expected))
+		}
+
+		for i, receipt := range listed {
+			if receipt.Id != added[c.expected[i]].Id {
+				t.Fatalf("%s: receipt %d is '%s', expected '%s'", c.description, i, receipt.Id, added[c.expected[i]].Id)
+			}
+		}
+	}
+
+	_, err := repo.ListReceipts(repositories.ReceiptFilter{}, uuid.New(), 10)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		t.Fatalf("ListReceipts returned '%v' after an unknown ID, expected ErrNotFound", err)
+	}
+}
+
+func testUpdatePoints(t *testing.T, repo repositories.ReceiptRepository) {
+	receipt := newReceipt("Target", 28)
+
+	if err := repo.AddReceipt(receipt); err != nil {
+		t.Fatalf("AddReceipt: %s", err)
+	}
+
+	previous, err := repo.UpdatePoints(receipt.Id, 48)
+	if err != nil {
+		t.Fatalf("UpdatePoints: %s", err)
+	}
+
+	if previous != 28 {
+		t.Fatalf("UpdatePoints returned previous points '%d', expected '%d'", previous, 28)
+	}
+
+	got, err := repo.ReceiptById(receipt.Id)
+	if err != nil {
+		t.Fatalf("ReceiptById: %s", err)
+	}
+
+	if got.Points != 48 || got.Retailer != receipt.Retailer {
+		t.Fatalf("ReceiptById returned %+v after UpdatePoints", got)
+	}
+
+	if _, err := repo.UpdatePoints(uuid.New(), 1); !errors.Is(err, repositories.ErrNotFound) {
+		t.Fatalf("UpdatePoints returned '%v' for an unknown ID, expected ErrNotFound", err)
+	}
+}
+
+func testConcurrentWriters(t *testing.T, repo repositories.ReceiptRepository) {
+	const writers = 50
+
+	receipts := make([]*entities.Receipt, writers)
+	for i := range receipts {
+		receipts[i] = newReceipt("Target", i)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+
+	for _, receipt := range receipts {
+		wg.Add(1)
+		go func(receipt *entities.Receipt) {
+			defer wg.Done()
+
+			if err := repo.AddReceipt(receipt); err != nil {
+				errs <- err
+				return
+			}
+
+			if _, err := repo.ReceiptById(receipt.Id); err != nil {
+				errs <- err
+			}
+		}(receipt)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Concurrent write failed: %s", err)
+	}
+
+	for _, receipt := range receipts {
+		got, err := repo.ReceiptById(receipt.Id)
+		if err != nil {
+			t.Fatalf("ReceiptById: %s", err)
+		}
+
+		if got.Points != receipt.Points {
+			t.Fatalf("Wrong points: '%d' expected '%d'", got.Points, receipt.Points)
+		}
+	}
+}
+
+func testConcurrentDuplicates(t *testing.T, repo repositories.ReceiptRepository) {
+	const writers = 20
+
+	id := uuid.New()
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	successes := 0
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(points int) {
+			defer wg.Done()
+
+			receipt := newReceipt("Target", points)
+			receipt.Id = id
+
+			err := repo.AddReceipt(receipt)
+			if err != nil && !errors.Is(err, repositories.ErrAlreadyExists) {
+				t.Errorf("AddReceipt: %s", err)
+			}
+
+			if err == nil {
+				mutex.Lock()
+				successes++
+				mutex.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if successes != 1 {
+		t.Fatalf("%d concurrent writers stored the same ID, expected 1", successes)
+	}
+}
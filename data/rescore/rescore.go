@@ -0,0 +1,322 @@
+// Package rescore recomputes the points of stored receipts in the
+// background, e.g. after a scoring bug is fixed. Jobs record every change in
+// an audit trail and checkpoint their progress so they can resume after a
+// crash.
+package rescore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+)
+
+var ErrJobRunning = errors.New("a rescoring job is already running")
+var ErrNoJob = errors.New("no rescoring job")
+
+// PAGE_SIZE is how many receipts a job reads at a time. Progress is
+// checkpointed after each page.
+const PAGE_SIZE = 500
+
+const CHECKPOINT_FILE = "checkpoint.json"
+const AUDIT_FILE = "audit.ndjson"
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusCancelled Status = "cancelled"
+	StatusFailed    Status = "failed"
+)
+
+// Progress describes a job. It's also the checkpoint a job resumes from.
+type Progress struct {
+	JobId   string                     `json:"jobId"`
+	Status  Status                     `json:"status"`
+	Ruleset entities.Ruleset           `json:"ruleset"`
+	Filter  repositories.ReceiptFilter `json:"filter"`
+	// Processed is the number of matching receipts examined so far, and
+	// LastReceiptId the last of them, which the job continues after.
+	Processed     int       `json:"processed"`
+	LastReceiptId uuid.UUID `json:"lastReceiptId"`
+	// Changed is the number of receipts whose points changed, and
+	// PointsDelta the total change in points.
+	Changed     int       `json:"changed"`
+	PointsDelta int       `json:"pointsDelta"`
+	StartedAt   time.Time `json:"startedAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// AuditRecord is written to the audit trail for every receipt whose points
+// a job changed.
+type AuditRecord struct {
+	JobId     string    `json:"jobId"`
+	ReceiptId string    `json:"receiptId"`
+	OldPoints int       `json:"oldPoints"`
+	NewPoints int       `json:"newPoints"`
+	At        time.Time `json:"at"`
+}
+
+// Manager runs at most one rescoring job at a time, keeping its checkpoint
+// and audit trail in a directory.
+type Manager struct {
+	repo repositories.ReceiptRepository
+	dir  string
+
+	progress *Progress
+	cancel   context.CancelFunc
+	done     chan struct{}
+	mutex    sync.Mutex
+}
+
+func NewManager(repo repositories.ReceiptRepository, dir string) *Manager {
+	return &Manager{repo: repo, dir: dir}
+}
+
+// Resume loads the last checkpoint, so its progress is reported, and
+// continues the job in the background if it was still running. It returns
+// whether a job was resumed.
+func (m *Manager) Resume() (bool, error) {
+	contents, err := os.ReadFile(filepath.Join(m.dir, CHECKPOINT_FILE))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var progress Progress
+	if err := json.Unmarshal(contents, &progress); err != nil {
+		return false, fmt.Errorf("%s: %w", CHECKPOINT_FILE, err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.progress = &progress
+	if progress.Status != StatusRunning {
+		return false, nil
+	}
+
+	log.Printf("Resuming rescoring job '%s' after %d receipts\n", progress.JobId, progress.Processed)
+	m.run()
+
+	return true, nil
+}
+
+// Start begins rescoring the receipts matching the filter with the ruleset.
+func (m *Manager) Start(ruleset entities.Ruleset, filter repositories.ReceiptFilter) (Progress, error) {
+	if err := ruleset.Validate(); err != nil {
+		return Progress{}, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.progress != nil && m.progress.Status == StatusRunning {
+		return Progress{}, ErrJobRunning
+	}
+
+	now := time.Now()
+	m.progress = &Progress{
+		JobId:     uuid.NewString(),
+		Status:    StatusRunning,
+		Ruleset:   ruleset,
+		Filter:    filter,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := m.saveCheckpoint(*m.progress); err != nil {
+		m.progress = nil
+		return Progress{}, err
+	}
+
+	m.run()
+
+	return *m.progress, nil
+}
+
+// Progress returns the current or most recent job's progress.
+func (m *Manager) Progress() (Progress, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.progress == nil {
+		return Progress{}, ErrNoJob
+	}
+
+	return *m.progress, nil
+}
+
+// Cancel stops the running job after the receipt it's working on.
+func (m *Manager) Cancel() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.progress == nil || m.progress.Status != StatusRunning {
+		return ErrNoJob
+	}
+
+	m.cancel()
+
+	return nil
+}
+
+// Wait blocks until the running job, if any, stops.
+func (m *Manager) Wait() {
+	m.mutex.Lock()
+	done := m.done
+	m.mutex.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// run starts the job in m.progress in the background. The caller must hold
+// the lock.
+func (m *Manager) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	go func(progress Progress, done chan struct{}) {
+		defer close(done)
+		defer cancel()
+
+		final := m.work(ctx, progress)
+		if err := m.update(final); err != nil {
+			log.Printf("Couldn't checkpoint rescoring job '%s': %s\n", final.JobId, err)
+		}
+	}(*m.progress, m.done)
+}
+
+// work rescores receipts from the job's checkpoint until it finishes, is
+// cancelled or fails, returning its final progress.
+func (m *Manager) work(ctx context.Context, progress Progress) Progress {
+	fail := func(err error) Progress {
+		log.Printf("Rescoring job '%s' failed: %s\n", progress.JobId, err)
+		progress.Status = StatusFailed
+		progress.Error = err.Error()
+		return progress
+	}
+
+	audit, err := os.OpenFile(
+		filepath.Join(m.dir, AUDIT_FILE), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644,
+	)
+	if err != nil {
+		return fail(err)
+	}
+	defer audit.Close()
+
+	encoder := json.NewEncoder(audit)
+
+	for {
+		page, err := m.repo.ListReceipts(progress.Filter, progress.LastReceiptId, PAGE_SIZE)
+		if err != nil {
+			return fail(err)
+		}
+
+		for _, receipt := range page {
+			if ctx.Err() != nil {
+				progress.Status = StatusCancelled
+				return progress
+			}
+
+			points := rescoredPoints(&progress.Ruleset, receipt)
+			if points != receipt.Points {
+				previous, err := m.repo.UpdatePoints(receipt.Id, points)
+				if err != nil {
+					return fail(err)
+				}
+
+				err = encoder.Encode(AuditRecord{
+					JobId:     progress.JobId,
+					ReceiptId: receipt.Id.String(),
+					OldPoints: previous,
+					NewPoints: points,
+					At:        time.Now(),
+				})
+				if err != nil {
+					return fail(err)
+				}
+
+				progress.Changed++
+				progress.PointsDelta += points - previous
+			}
+
+			progress.Processed++
+			progress.LastReceiptId = receipt.Id
+		}
+
+		// The audit trail must never be behind the checkpoint, or changes
+		// made before a crash would go unrecorded.
+		if err := audit.Sync(); err != nil {
+			return fail(err)
+		}
+
+		if len(page) < PAGE_SIZE {
+			progress.Status = StatusCompleted
+			return progress
+		}
+
+		if err := m.update(progress); err != nil {
+			return fail(err)
+		}
+	}
+}
+
+// rescoredPoints returns the receipt's points under the ruleset, with the
+// promotions it got when it was processed applied again to the new points
+// from the rules. Their terms are the ones recorded then, so later changes
+// to a promotion don't affect receipts it already applied to.
+func rescoredPoints(ruleset *entities.Ruleset, r *entities.Receipt) int {
+	basePoints := ruleset.CountPoints(r)
+	points := basePoints
+	for _, promotion := range r.Promotions {
+		points += promotion.PointsFor(basePoints)
+	}
+	return points
+}
+
+// update records the job's progress and checkpoints it.
+func (m *Manager) update(progress Progress) error {
+	progress.UpdatedAt = time.Now()
+
+	m.mutex.Lock()
+	m.progress = &progress
+	m.mutex.Unlock()
+
+	return m.saveCheckpoint(progress)
+}
+
+// saveCheckpoint writes the checkpoint atomically, so a crash leaves either
+// the old or the new one.
+func (m *Manager) saveCheckpoint(progress Progress) error {
+	contents, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	temp := filepath.Join(m.dir, CHECKPOINT_FILE+".tmp")
+	if err := os.WriteFile(temp, contents, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, filepath.Join(m.dir, CHECKPOINT_FILE))
+}
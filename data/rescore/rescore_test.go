@@ -0,0 +1,225 @@
+package rescore
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/repositories"
+	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+)
+
+// addStaleReceipts stores receipts whose points are out of date, returning
+// them in the order they were added.
+func addStaleReceipts(t *testing.T, repo repositories.ReceiptRepository, count int) []*entities.Receipt {
+	receipts := make([]*entities.Receipt, count)
+	for i := range receipts {
+		receipts[i] = &entities.Receipt{
+			Id:               uuid.New(),
+			Retailer:         "Target",
+			PurchaseDateTime: time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC),
+			Total:            1.10,
+			Points:           1,
+		}
+
+		if err := repo.AddReceipt(receipts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return receipts
+}
+
+func readAudit(t *testing.T, dir string) []AuditRecord {
+	file, err := os.Open(filepath.Join(dir, AUDIT_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRescore(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	dir := t.TempDir()
+	receipts := addStaleReceipts(t, repo, 3)
+
+	// One receipt is already up to date.
+	expected := entities.CountPoints(receipts[0])
+	if _, err := repo.UpdatePoints(receipts[0].Id, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(repo, dir)
+	if _, err := manager.Progress(); err != ErrNoJob {
+		t.Fatalf("Wrong error before any job: '%v' expected '%v'", err, ErrNoJob)
+	}
+
+	if _, err := manager.Start(entities.DefaultRuleset, repositories.ReceiptFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	manager.Wait()
+
+	progress, err := manager.Progress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if progress.Status != StatusCompleted || progress.Processed != 3 || progress.Changed != 2 {
+		t.Fatalf("Wrong progress: %+v", progress)
+	}
+
+	if progress.PointsDelta != 2*(expected-1) {
+		t.Fatalf("Wrong points delta: '%d' expected '%d'", progress.PointsDelta, 2*(expected-1))
+	}
+
+	for _, receipt := range receipts {
+		stored, err := repo.ReceiptById(receipt.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stored.Points != expected {
+			t.Fatalf("Wrong points: '%d' expected '%d'", stored.Points, expected)
+		}
+	}
+
+	records := readAudit(t, dir)
+	if len(records) != 2 {
+		t.Fatalf("Wrong number of audit records: '%d' expected '%d'", len(records), 2)
+	}
+
+	if records[0].ReceiptId != receipts[1].Id.String() ||
+		records[0].OldPoints != 1 || records[0].NewPoints != expected {
+		t.Fatalf("Wrong audit record: %+v", records[0])
+	}
+
+	// A new manager only reports the finished job.
+	restarted := NewManager(repo, dir)
+	resumed, err := restarted.Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resumed {
+		t.Fatal("Resumed a completed job")
+	}
+
+	if progress, _ := restarted.Progress(); progress.JobId != records[0].JobId {
+		t.Fatalf("Wrong job: '%s' expected '%s'", progress.JobId, records[0].JobId)
+	}
+}
+
+func TestRescoreReappliesPromotions(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	receipt := &entities.Receipt{
+		Id:               uuid.New(),
+		Retailer:         "Target",
+		PurchaseDateTime: time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC),
+		Total:            1.10,
+		Points:           102,
+		Promotions: []entities.PromotionPoints{
+			{PromotionId: "double", Points: 1, Multiplier: 2},
+			{PromotionId: "bonus", Points: 100, Bonus: 100},
+		},
+	}
+
+	if err := repo.AddReceipt(receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(repo, t.TempDir())
+	if _, err := manager.Start(entities.DefaultRuleset, repositories.ReceiptFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	manager.Wait()
+
+	stored, err := repo.ReceiptById(receipt.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The multiplier doubles the new points from the rules, not the old.
+	basePoints := entities.CountPoints(receipt)
+	if expected := 2*basePoints + 100; stored.Points != expected {
+		t.Fatalf("Wrong points: '%d' expected '%d'", stored.Points, expected)
+	}
+}
+
+func TestResume(t *testing.T) {
+	repo := inmemory.NewInMemoryReceiptRepository()
+	dir := t.TempDir()
+	receipts := addStaleReceipts(t, repo, 4)
+
+	// The job crashed after the first two receipts.
+	manager := NewManager(repo, dir)
+	err := manager.saveCheckpoint(Progress{
+		JobId:         "crashed",
+		Status:        StatusRunning,
+		Ruleset:       entities.DefaultRuleset,
+		Processed:     2,
+		LastReceiptId: receipts[1].Id,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := manager.Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resumed {
+		t.Fatal("Didn't resume a running job")
+	}
+	manager.Wait()
+
+	if _, err := manager.Start(entities.DefaultRuleset, repositories.ReceiptFilter{}); err == ErrJobRunning {
+		t.Fatal("Job still running after it finished")
+	}
+	manager.Wait()
+
+	records := readAudit(t, dir)
+	if len(records) != 4 {
+		t.Fatalf("Wrong number of audit records: '%d' expected '%d'", len(records), 4)
+	}
+
+	// The resumed job only rescored the receipts after the checkpoint, and
+	// the new job rescored the rest.
+	for i, receipt := range append(receipts[2:], receipts[:2]...) {
+		if records[i].ReceiptId != receipt.Id.String() {
+			t.Fatalf("Wrong receipt audited: '%s' expected '%s'", records[i].ReceiptId, receipt.Id)
+		}
+	}
+
+	if records[0].JobId != "crashed" || records[2].JobId == "crashed" {
+		t.Fatalf("Wrong jobs audited: %+v", records)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	manager := NewManager(inmemory.NewInMemoryReceiptRepository(), t.TempDir())
+	if err := manager.Cancel(); err != ErrNoJob {
+		t.Fatalf("Wrong error: '%v' expected '%v'", err, ErrNoJob)
+	}
+
+	invalid := entities.DefaultRuleset
+	invalid.AfternoonStartHour = 25
+	if _, err := manager.Start(invalid, repositories.ReceiptFilter{}); err == nil {
+		t.Fatal("Started a job with an invalid ruleset")
+	}
+}
@@ -0,0 +1,82 @@
+package retailers
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// storeNumberPattern matches a store number at the end of a name, such as
+// "#1234", "Store 12" or "No. 7", or a bare number of three or more digits.
+// Shorter bare numbers are kept since they're often part of the name, as in
+// "Studio 54". The words must stand alone, so "Casino 5" keeps its number.
+var storeNumberPattern = regexp.MustCompile(
+	`\s*(?:#\s*|\b(?:store|no\.?|location)\s*#?\s*)\d+$|\s+\d{3,}$`,
+)
+
+var caseFolder = cases.Fold()
+
+// Normalize reduces a retailer name to the form aliases are matched on: NFC,
+// case folded, without a trailing store number, without apostrophes and
+// periods, and with any other punctuation and runs of whitespace replaced by
+// single spaces.
+func Normalize(name string) string {
+	name = caseFolder.String(norm.NFC.String(name))
+	name = strings.TrimSpace(name)
+	name = storeNumberPattern.ReplaceAllString(name, "")
+
+	var b strings.Builder
+	for _, c := range name {
+		switch {
+		case c == '\'' || c == '’' || c == '.':
+			continue
+		case unicode.IsLetter(c) || unicode.IsMark(c) || unicode.IsDigit(c) || c == '&':
+			b.WriteRune(c)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// similarity returns how alike two normalized names are, from 0 for nothing
+// in common to 1 for identical, as one minus their edit distance over the
+// length of the longer name.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions needed to turn a into b.
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
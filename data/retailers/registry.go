@@ -0,0 +1,270 @@
+package retailers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+var ErrNotFound = errors.New("retailer not found")
+var ErrAlreadyExists = errors.New("retailer already exists")
+var ErrAliasConflict = errors.New("alias belongs to another retailer")
+var ErrInvalidRetailer = errors.New("invalid retailer")
+
+// DEFAULT_FUZZY_THRESHOLD is the similarity a name needs to a known name or
+// alias to match it without an exact alias, e.g. "Walgreen" for "Walgreens".
+const DEFAULT_FUZZY_THRESHOLD = 0.85
+
+var retailerIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9\-]{0,63}$`)
+
+// Retailer is a canonical retailer. Receipts whose retailer name matches the
+// name or one of the aliases are attributed to its Id.
+type Retailer struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// Registry resolves retailer names on receipts to canonical retailer ids. It
+// is safe for concurrent use.
+type Registry struct {
+	threshold float64
+	retailers map[string]*Retailer
+	// names maps each normalized name and alias to its retailer's id.
+	names map[string]string
+	mutex sync.RWMutex
+}
+
+// NewRegistry returns an empty registry. Names at least threshold similar to
+// a known name are matched fuzzily; a threshold above 1 only allows matches
+// after normalization.
+func NewRegistry(threshold float64) *Registry {
+	return &Registry{
+		threshold: threshold,
+		retailers: make(map[string]*Retailer),
+		names:     make(map[string]string),
+	}
+}
+
+type registryFile struct {
+	Threshold *float64   `json:"threshold"`
+	Retailers []Retailer `json:"retailers"`
+}
+
+// LoadRegistry reads a registry from a JSON file of the form
+//
+//	{"threshold": 0.85, "retailers": [{"id": "walgreens", "name": "Walgreens", "aliases": ["Walgreens Pharmacy"]}]}
+//
+// The threshold defaults to DEFAULT_FUZZY_THRESHOLD.
+func LoadRegistry(path string) (*Registry, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	threshold := DEFAULT_FUZZY_THRESHOLD
+	if file.Threshold != nil {
+		threshold = *file.Threshold
+	}
+
+	registry := NewRegistry(threshold)
+	for _, retailer := range file.Retailers {
+		if err := registry.Add(retailer); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, retailer.Id, err)
+		}
+	}
+
+	return registry, nil
+}
+
+// Add registers a retailer under its name and aliases.
+func (r *Registry) Add(retailer Retailer) error {
+	if !retailerIdPattern.MatchString(retailer.Id) || Normalize(retailer.Name) == "" {
+		return ErrInvalidRetailer
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.retailers[retailer.Id]; ok {
+		return ErrAlreadyExists
+	}
+
+	names := append([]string{retailer.Name}, retailer.Aliases...)
+	for _, name := range names {
+		if err := r.checkAlias(retailer.Id, name); err != nil {
+			return err
+		}
+	}
+
+	r.retailers[retailer.Id] = &Retailer{
+		Id:      retailer.Id,
+		Name:    retailer.Name,
+		Aliases: []string{},
+	}
+	r.names[Normalize(retailer.Name)] = retailer.Id
+
+	for _, alias := range retailer.Aliases {
+		r.addAlias(retailer.Id, alias)
+	}
+
+	return nil
+}
+
+// checkAlias returns an error if alias can't be added to the retailer with
+// the given id. The caller must hold the lock.
+func (r *Registry) checkAlias(id, alias string) error {
+	normalized := Normalize(alias)
+	if normalized == "" {
+		return ErrInvalidRetailer
+	}
+
+	if owner, ok := r.names[normalized]; ok && owner != id {
+		return ErrAliasConflict
+	}
+
+	return nil
+}
+
+// addAlias adds a checked alias. The caller must hold the lock.
+func (r *Registry) addAlias(id, alias string) {
+	normalized := Normalize(alias)
+	retailer := r.retailers[id]
+	r.names[normalized] = id
+
+	if normalized == Normalize(retailer.Name) {
+		return
+	}
+
+	for _, existing := range retailer.Aliases {
+		if Normalize(existing) == normalized {
+			return
+		}
+	}
+
+	retailer.Aliases = append(retailer.Aliases, alias)
+}
+
+// AddAlias adds an alias to an existing retailer.
+func (r *Registry) AddAlias(id, alias string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.retailers[id]; !ok {
+		return ErrNotFound
+	}
+
+	if err := r.checkAlias(id, alias); err != nil {
+		return err
+	}
+
+	r.addAlias(id, alias)
+
+	return nil
+}
+
+// RemoveAlias removes an alias from a retailer. The retailer's own name
+// can't be removed.
+func (r *Registry) RemoveAlias(id, alias string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	retailer, ok := r.retailers[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	normalized := Normalize(alias)
+	for i, existing := range retailer.Aliases {
+		if Normalize(existing) == normalized {
+			retailer.Aliases = append(retailer.Aliases[:i], retailer.Aliases[i+1:]...)
+			if Normalize(retailer.Name) != normalized {
+				delete(r.names, normalized)
+			}
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// Retailer returns a copy of the retailer with the given id.
+func (r *Registry) Retailer(id string) (Retailer, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	retailer, ok := r.retailers[id]
+	if !ok {
+		return Retailer{}, ErrNotFound
+	}
+
+	return copyRetailer(retailer), nil
+}
+
+// Retailers returns copies of every retailer, ordered by id.
+func (r *Registry) Retailers() []Retailer {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	retailers := make([]Retailer, 0, len(r.retailers))
+	for _, retailer := range r.retailers {
+		retailers = append(retailers, copyRetailer(retailer))
+	}
+
+	sort.Slice(retailers, func(i, j int) bool {
+		return retailers[i].Id < retailers[j].Id
+	})
+
+	return retailers
+}
+
+func copyRetailer(r *Retailer) Retailer {
+	return Retailer{
+		Id:      r.Id,
+		Name:    r.Name,
+		Aliases: append([]string{}, r.Aliases...),
+	}
+}
+
+// Resolve returns the id of the retailer a receipt's retailer name refers
+// to. Names are matched after normalization, then fuzzily against every
+// known name and alias. It returns false if no retailer matches.
+func (r *Registry) Resolve(name string) (string, bool) {
+	normalized := Normalize(name)
+	if normalized == "" {
+		return "", false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if id, ok := r.names[normalized]; ok {
+		return id, true
+	}
+
+	// Prefer the most similar name, breaking ties by the name itself so the
+	// result doesn't depend on map order.
+	var bestId, bestName string
+	var bestSimilarity float64
+	for known, id := range r.names {
+		s := similarity(normalized, known)
+		if s < r.threshold {
+			continue
+		}
+
+		if bestId == "" || s > bestSimilarity || (s == bestSimilarity && known < bestName) {
+			bestId, bestName, bestSimilarity = id, known, s
+		}
+	}
+
+	return bestId, bestId != ""
+}
@@ -0,0 +1,151 @@
+package retailers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"Walgreens":           "walgreens",
+		"WALGREENS #1234":     "walgreens",
+		"Walgreens Store 12":  "walgreens",
+		"Walgreens 05521":     "walgreens",
+		"Studio 54":           "studio 54",
+		"M&M Corner Market":   "m&m corner market",
+		"Trader Joe's":        "trader joes",
+		"  7-Eleven  No. 33 ": "7 eleven",
+		"STRASSE":             "strasse",
+		"Straße":              "strasse",
+		"Café":                "café",
+		"Casino 5":            "casino 5",
+		"Domino 7":            "domino 7",
+		"Restore 12":          "restore 12",
+		"Casino #5":           "casino",
+	}
+
+	for name, expected := range cases {
+		if normalized := Normalize(name); normalized != expected {
+			t.Fatalf("Wrong normalized name for '%s': '%s' expected '%s'", name, normalized, expected)
+		}
+	}
+}
+
+func makeRegistry(t *testing.T) *Registry {
+	registry := NewRegistry(DEFAULT_FUZZY_THRESHOLD)
+
+	retailers := []Retailer{
+		{Id: "walgreens", Name: "Walgreens", Aliases: []string{"Walgreens Pharmacy"}},
+		{Id: "target", Name: "Target"},
+	}
+
+	for _, retailer := range retailers {
+		if err := registry.Add(retailer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return registry
+}
+
+func TestResolve(t *testing.T) {
+	registry := makeRegistry(t)
+
+	cases := []struct {
+		name string
+		id   string
+	}{
+		{"Walgreens", "walgreens"},
+		{"WALGREENS #1234", "walgreens"},
+		{"Walgreens Pharmacy", "walgreens"},
+		{"walgreen", "walgreens"},
+		{"Walgreens Pharmacyy", "walgreens"},
+		{"TARGET", "target"},
+		{"Tarjay", ""},
+		{"CVS", ""},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		id, ok := registry.Resolve(c.name)
+		if id != c.id || ok != (c.id != "") {
+			t.Fatalf("Wrong retailer for '%s': '%s' expected '%s'", c.name, id, c.id)
+		}
+	}
+
+	exact := makeRegistry(t)
+	exact.threshold = 1.01
+	if id, ok := exact.Resolve("walgreen"); ok {
+		t.Fatalf("Fuzzy match '%s' with fuzzy matching off", id)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	registry := makeRegistry(t)
+
+	if err := registry.AddAlias("target", "Tarjay"); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, _ := registry.Resolve("TARJAY #12"); id != "target" {
+		t.Fatalf("Wrong retailer for new alias: '%s' expected '%s'", id, "target")
+	}
+
+	if err := registry.AddAlias("target", "walgreens pharmacy"); !errors.Is(err, ErrAliasConflict) {
+		t.Fatalf("Wrong error for a conflicting alias: %v", err)
+	}
+
+	if err := registry.AddAlias("cvs", "CVS Pharmacy"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Wrong error for an unknown retailer: %v", err)
+	}
+
+	if err := registry.RemoveAlias("target", "tarjay"); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, ok := registry.Resolve("Tarjay"); ok {
+		t.Fatalf("Removed alias still resolves to '%s'", id)
+	}
+
+	if err := registry.Add(Retailer{Id: "target", Name: "Target Optical"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("Wrong error for a duplicate id: %v", err)
+	}
+
+	if err := registry.Add(Retailer{Id: "Bad Id", Name: "Bad"}); !errors.Is(err, ErrInvalidRetailer) {
+		t.Fatalf("Wrong error for an invalid id: %v", err)
+	}
+
+	retailer, err := registry.Retailer("walgreens")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(retailer.Aliases) != 1 || retailer.Aliases[0] != "Walgreens Pharmacy" {
+		t.Fatalf("Wrong aliases: %v", retailer.Aliases)
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "retailers.json")
+	contents := `{"threshold": 1.01, "retailers": [` +
+		`{"id": "walgreens", "name": "Walgreens", "aliases": ["Walgreens Pharmacy"]}]}`
+
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, _ := registry.Resolve("Walgreens Pharmacy #9"); id != "walgreens" {
+		t.Fatalf("Wrong retailer: '%s' expected '%s'", id, "walgreens")
+	}
+
+	if _, ok := registry.Resolve("Walgreen"); ok {
+		t.Fatal("Loaded threshold wasn't applied")
+	}
+}
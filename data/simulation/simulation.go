@@ -0,0 +1,135 @@
+// Package simulation compares the points a candidate ruleset would award
+// against the current ruleset, without changing any stored receipt.
+package simulation
+
+import (
+	"math"
+	"sort"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+// MAX_BIGGEST_MOVERS is how many receipts with the largest changes in points
+// a result lists.
+const MAX_BIGGEST_MOVERS = 10
+
+// ReceiptDelta compares the points for one receipt.
+type ReceiptDelta struct {
+	// Index is the receipt's position in the simulated receipts.
+	Index           int    `json:"index"`
+	Id              string `json:"id"`
+	Retailer        string `json:"retailer"`
+	CurrentPoints   int    `json:"currentPoints"`
+	CandidatePoints int    `json:"candidatePoints"`
+	Delta           int    `json:"delta"`
+}
+
+// Statistics summarizes the points awarded to a set of receipts.
+// Percentiles use the nearest-rank method.
+type Statistics struct {
+	Total int     `json:"total"`
+	Mean  float64 `json:"mean"`
+	Min   int     `json:"min"`
+	P50   int     `json:"p50"`
+	P90   int     `json:"p90"`
+	P99   int     `json:"p99"`
+	Max   int     `json:"max"`
+}
+
+type Result struct {
+	Count      int        `json:"count"`
+	Current    Statistics `json:"current"`
+	Candidate  Statistics `json:"candidate"`
+	TotalDelta int        `json:"totalDelta"`
+	// BiggestMovers lists the receipts whose points changed the most,
+	// largest change first.
+	BiggestMovers []ReceiptDelta `json:"biggestMovers"`
+	Receipts      []ReceiptDelta `json:"receipts"`
+}
+
+// Run scores the receipts with both rulesets. Only the rules are compared;
+// promotions aren't applied.
+func Run(current, candidate *entities.Ruleset, receipts []*entities.Receipt) Result {
+	deltas := make([]ReceiptDelta, len(receipts))
+	currentPoints := make([]int, len(receipts))
+	candidatePoints := make([]int, len(receipts))
+
+	for i, r := range receipts {
+		currentPoints[i] = current.CountPoints(r)
+		candidatePoints[i] = candidate.CountPoints(r)
+
+		deltas[i] = ReceiptDelta{
+			Index:           i,
+			Id:              r.Id.String(),
+			Retailer:        r.Retailer,
+			CurrentPoints:   currentPoints[i],
+			CandidatePoints: candidatePoints[i],
+			Delta:           candidatePoints[i] - currentPoints[i],
+		}
+	}
+
+	result := Result{
+		Count:         len(receipts),
+		Current:       summarize(currentPoints),
+		Candidate:     summarize(candidatePoints),
+		BiggestMovers: biggestMovers(deltas),
+		Receipts:      deltas,
+	}
+	result.TotalDelta = result.Candidate.Total - result.Current.Total
+
+	return result
+}
+
+func summarize(points []int) Statistics {
+	if len(points) == 0 {
+		return Statistics{}
+	}
+
+	sorted := append([]int{}, points...)
+	sort.Ints(sorted)
+
+	var total int
+	for _, p := range sorted {
+		total += p
+	}
+
+	return Statistics{
+		Total: total,
+		Mean:  float64(total) / float64(len(sorted)),
+		Min:   sorted[0],
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted points.
+func percentile(sorted []int, p float64) int {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// biggestMovers returns the receipts whose points changed, by the size of
+// the change and then by index.
+func biggestMovers(deltas []ReceiptDelta) []ReceiptDelta {
+	movers := make([]ReceiptDelta, 0, len(deltas))
+	for _, d := range deltas {
+		if d.Delta != 0 {
+			movers = append(movers, d)
+		}
+	}
+
+	sort.SliceStable(movers, func(i, j int) bool {
+		return absInt(movers[i].Delta) > absInt(movers[j].Delta)
+	})
+
+	return movers[:min(len(movers), MAX_BIGGEST_MOVERS)]
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,63 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/entities"
+)
+
+func TestRun(t *testing.T) {
+	receipts := make([]*entities.Receipt, 20)
+	for i := range receipts {
+		receipts[i] = &entities.Receipt{
+			Id:               uuid.New(),
+			Retailer:         "Target",
+			PurchaseDateTime: time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC),
+			Total:            float64(i) + 0.10,
+		}
+	}
+
+	// Every fifth receipt has a round total.
+	for i := 0; i < len(receipts); i += 5 {
+		receipts[i].Total = float64(i)
+	}
+
+	candidate := entities.DefaultRuleset
+	candidate.RoundDollarPoints = 100
+
+	result := Run(&entities.DefaultRuleset, &candidate, receipts)
+
+	if result.Count != 20 || len(result.Receipts) != 20 {
+		t.Fatalf("Wrong number of receipts: '%d' expected '%d'", result.Count, 20)
+	}
+
+	// Round totals earn 6 + 50 + 25 + 20 points now and 50 more with the
+	// candidate; the others earn 6 + 20.
+	if result.Current.Total != 4*101+16*26 {
+		t.Fatalf("Wrong current total: '%d' expected '%d'", result.Current.Total, 4*101+16*26)
+	}
+
+	if result.TotalDelta != 4*50 {
+		t.Fatalf("Wrong total delta: '%d' expected '%d'", result.TotalDelta, 4*50)
+	}
+
+	if result.Current.P50 != 26 || result.Current.P90 != 101 || result.Current.Max != 101 {
+		t.Fatalf("Wrong percentiles: %+v", result.Current)
+	}
+
+	if result.Candidate.Max != 151 || result.Candidate.Mean != float64(4*151+16*26)/20 {
+		t.Fatalf("Wrong candidate statistics: %+v", result.Candidate)
+	}
+
+	if len(result.BiggestMovers) != 4 || result.BiggestMovers[0].Index != 0 ||
+		result.BiggestMovers[3].Index != 15 {
+		t.Fatalf("Wrong biggest movers: %+v", result.BiggestMovers)
+	}
+
+	empty := Run(&entities.DefaultRuleset, &candidate, nil)
+	if empty.Count != 0 || len(empty.BiggestMovers) != 0 {
+		t.Fatalf("Wrong result for no receipts: %+v", empty)
+	}
+}
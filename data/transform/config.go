@@ -0,0 +1,31 @@
+package transform
+
+import (
+	"errors"
+
+	"github.com/vimolicious/receipt-processor/data/currency"
+	"github.com/vimolicious/receipt-processor/data/retailers"
+)
+
+// ErrInvalidReceipt wraps errors caused by the receipt's contents rather
+// than by the server, such as a currency without an exchange rate.
+var ErrInvalidReceipt = errors.New("invalid receipt")
+
+var exchangeRates = currency.NewRateTable(currency.DEFAULT_CURRENCY)
+
+// SetExchangeRates changes the table used to convert receipts into the base
+// currency for scoring, and makes its base the currency of receipts without
+// one. It should be called during startup.
+func SetExchangeRates(t *currency.RateTable) {
+	exchangeRates = t
+	currency.SetBaseCurrency(t.Base())
+}
+
+var retailerRegistry = retailers.NewRegistry(retailers.DEFAULT_FUZZY_THRESHOLD)
+
+// SetRetailers changes the registry retailer names are resolved against.
+// The registry may be changed while receipts are processed, but the registry
+// itself should only be replaced during startup.
+func SetRetailers(r *retailers.Registry) {
+	retailerRegistry = r
+}
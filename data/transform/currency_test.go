@@ -0,0 +1,49 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/currency"
+)
+
+func TestReceiptsWithoutCurrencyUseTheBase(t *testing.T) {
+	rateTable := currency.NewRateTable("EUR")
+	err := rateTable.Add(currency.Rate{
+		Currency:  "USD",
+		Effective: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Rate:      0.90,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetExchangeRates(rateTable)
+	defer SetExchangeRates(currency.NewRateTable(currency.DEFAULT_CURRENCY))
+
+	receipt, err := ReceiptModelToEntity(makeModel("Target", "2022-01-01", "13:01", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.Currency != "" || receipt.ExchangeRate != 1 {
+		t.Fatalf("Wrong currency: '%s' at '%f' expected the base at '1'", receipt.Currency, receipt.ExchangeRate)
+	}
+
+	if currency.BaseCurrency() != "EUR" {
+		t.Fatalf("Wrong base currency: '%s' expected 'EUR'", currency.BaseCurrency())
+	}
+
+	usd := "USD"
+	model := makeModel("Target", "2022-01-01", "13:01", nil)
+	model.Currency = &usd
+
+	receipt, err = ReceiptModelToEntity(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.ExchangeRate != 0.90 {
+		t.Fatalf("Wrong exchange rate: '%f' expected '0.90'", receipt.ExchangeRate)
+	}
+}
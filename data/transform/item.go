@@ -1,21 +1,43 @@
 package transform
 
 import (
-	"fmt"
 	"strconv"
 
+	"github.com/vimolicious/receipt-processor/data/currency"
 	"github.com/vimolicious/receipt-processor/data/entities"
 	"github.com/vimolicious/receipt-processor/data/models"
 )
 
 func ItemEntityToModel(i *entities.Item) (*models.Item, error) {
-	price := fmt.Sprintf("%.2f", i.Price)
+	return itemEntityToModel(i, "")
+}
+
+func itemEntityToModel(i *entities.Item, currencyCode string) (*models.Item, error) {
+	price := currency.FormatAmount(i.Price, currencyCode)
 
 	item := models.Item{
 		ShortDescription: &i.ShortDescription,
 		Price:            &price,
 	}
 
+	if i.Quantity != 0 {
+		quantity := i.Quantity
+		item.Quantity = &quantity
+	}
+
+	if i.UnitPrice != 0 {
+		unitPrice := currency.FormatAmount(i.UnitPrice, currencyCode)
+		item.UnitPrice = &unitPrice
+	}
+
+	if i.SKU != "" {
+		item.SKU = &i.SKU
+	}
+
+	if i.UPC != "" {
+		item.UPC = &i.UPC
+	}
+
 	return &item, nil
 }
 
@@ -30,5 +52,25 @@ func ItemModelToEntity(i *models.Item) (*entities.Item, error) {
 		Price:            price,
 	}
 
+	if i.Quantity != nil {
+		item.Quantity = *i.Quantity
+	}
+
+	if i.UnitPrice != nil {
+		unitPrice, err := strconv.ParseFloat(*i.UnitPrice, 64)
+		if err != nil {
+			return nil, err
+		}
+		item.UnitPrice = unitPrice
+	}
+
+	if i.SKU != nil {
+		item.SKU = *i.SKU
+	}
+
+	if i.UPC != nil {
+		item.UPC = *i.UPC
+	}
+
 	return &item, nil
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vimolicious/receipt-processor/data/currency"
 	"github.com/vimolicious/receipt-processor/data/entities"
 	"github.com/vimolicious/receipt-processor/data/models"
 )
@@ -13,11 +14,11 @@ import (
 func ReceiptEntityToModel(r *entities.Receipt) (*models.Receipt, error) {
 	purchaseDate := r.PurchaseDateTime.Format("2006-01-02")
 	purchaseTime := r.PurchaseDateTime.Format("15:04")
-	total := fmt.Sprintf("%.2f", r.Total)
+	total := currency.FormatAmount(r.Total, r.Currency)
 	items := make([]models.Item, len(r.Items))
 
 	for i, ri := range r.Items {
-		item, err := ItemEntityToModel(&ri)
+		item, err := itemEntityToModel(&ri, r.Currency)
 		if err != nil {
 			return nil, err
 		}
@@ -33,13 +34,60 @@ func ReceiptEntityToModel(r *entities.Receipt) (*models.Receipt, error) {
 		Total:        &total,
 	}
 
+	if r.Currency != "" {
+		receipt.Currency = &r.Currency
+	}
+
+	optionalAmounts := []struct {
+		amount *float64
+		field  **string
+	}{
+		{r.Subtotal, &receipt.Subtotal},
+		{r.Tax, &receipt.Tax},
+		{r.Tip, &receipt.Tip},
+	}
+
+	for _, a := range optionalAmounts {
+		if a.amount != nil {
+			formatted := currency.FormatAmount(*a.amount, r.Currency)
+			*a.field = &formatted
+		}
+	}
+
+	if len(r.Discounts) > 0 {
+		discounts := make([]models.Discount, len(r.Discounts))
+		for i, d := range r.Discounts {
+			description := d.Description
+			amount := currency.FormatAmount(d.Amount, r.Currency)
+			discounts[i] = models.Discount{Description: &description, Amount: &amount}
+		}
+		receipt.Discounts = &discounts
+	}
+
+	if r.AccountId != "" {
+		receipt.AccountId = &r.AccountId
+	}
+
+	if location := r.PurchaseDateTime.Location(); location != time.UTC {
+		timezone := location.String()
+		receipt.Timezone = &timezone
+	}
+
 	return &receipt, nil
 }
 
 func ReceiptModelToEntity(r *models.Receipt) (*entities.Receipt, error) {
-	purchaseDateTime, err := time.Parse(
-		"2006-01-02 15:04",
-		fmt.Sprintf("%s %s", *r.PurchaseDate, *r.PurchaseTime),
+	location := timezones.location(*r.Retailer)
+	if r.Timezone != nil {
+		tz, err := time.LoadLocation(*r.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidReceipt, err)
+		}
+		location = tz
+	}
+
+	purchaseDateTime, err := parsePurchaseDateTime(
+		*r.PurchaseDate, *r.PurchaseTime, location,
 	)
 	if err != nil {
 		return nil, err
@@ -65,15 +113,86 @@ func ReceiptModelToEntity(r *models.Receipt) (*entities.Receipt, error) {
 		items[i] = *item
 	}
 
+	subtotal, err := parseOptionalAmount(r.Subtotal)
+	if err != nil {
+		return nil, err
+	}
+
+	tax, err := parseOptionalAmount(r.Tax)
+	if err != nil {
+		return nil, err
+	}
+
+	tip, err := parseOptionalAmount(r.Tip)
+	if err != nil {
+		return nil, err
+	}
+
+	var discounts []entities.Discount
+	if r.Discounts != nil {
+		discounts = make([]entities.Discount, len(*r.Discounts))
+		for i, rd := range *r.Discounts {
+			amount, err := strconv.ParseFloat(*rd.Amount, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			discounts[i] = entities.Discount{
+				Description: *rd.Description,
+				Amount:      amount,
+			}
+		}
+	}
+
+	var currencyCode string
+	if r.Currency != nil {
+		currencyCode = *r.Currency
+	}
+
+	exchangeRate, err := exchangeRates.Rate(currencyCode, purchaseDateTime)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidReceipt, err)
+	}
+
+	retailerId, _ := retailerRegistry.Resolve(*r.Retailer)
+
+	var accountId string
+	if r.AccountId != nil {
+		accountId = *r.AccountId
+	}
+
 	receipt := entities.Receipt{
 		Items:            items,
 		Retailer:         *r.Retailer,
+		RetailerId:       retailerId,
 		PurchaseDateTime: purchaseDateTime,
 		Total:            total,
 		Id:               id,
+		Currency:         currencyCode,
+		ExchangeRate:     exchangeRate,
+		Subtotal:         subtotal,
+		Tax:              tax,
+		Tip:              tip,
+		Discounts:        discounts,
+		AccountId:        accountId,
 	}
 
 	receipt.Points = entities.CountPoints(&receipt)
 
 	return &receipt, nil
 }
+
+// parseOptionalAmount parses an amount the receipt may leave out, returning
+// nil when it's missing.
+func parseOptionalAmount(amount *string) (*float64, error) {
+	if amount == nil {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseFloat(*amount, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
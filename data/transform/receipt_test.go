@@ -0,0 +1,95 @@
+package transform
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"testing/quick"
+
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/entities/entitytest"
+	"github.com/vimolicious/receipt-processor/data/models"
+)
+
+func sameCents(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
+
+func sameOptionalCents(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return sameCents(*a, *b)
+}
+
+// Receipts survive a trip through the model, including JSON encoding and
+// validation, and back.
+func TestReceiptRoundTrip(t *testing.T) {
+	property := func(r entitytest.Receipt) bool {
+		model, err := ReceiptEntityToModel(&r.Receipt)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+
+		encoded, err := json.Marshal(model)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+
+		var decoded models.Receipt
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Logf("%s: %s", encoded, err)
+			return false
+		}
+
+		roundTripped, err := ReceiptModelToEntity(&decoded)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+
+		if roundTripped.Retailer != r.Retailer ||
+			roundTripped.AccountId != r.AccountId ||
+			!roundTripped.PurchaseDateTime.Equal(r.PurchaseDateTime) ||
+			!sameCents(roundTripped.Total, r.Total) ||
+			!sameOptionalCents(roundTripped.Subtotal, r.Subtotal) ||
+			!sameOptionalCents(roundTripped.Tax, r.Tax) ||
+			!sameOptionalCents(roundTripped.Tip, r.Tip) ||
+			len(roundTripped.Discounts) != len(r.Discounts) ||
+			len(roundTripped.Items) != len(r.Items) {
+			return false
+		}
+
+		for i, item := range roundTripped.Items {
+			if item.ShortDescription != r.Items[i].ShortDescription ||
+				!sameCents(item.Price, r.Items[i].Price) ||
+				item.Quantity != r.Items[i].Quantity ||
+				!sameCents(item.UnitPrice, r.Items[i].UnitPrice) ||
+				item.SKU != r.Items[i].SKU {
+				return false
+			}
+		}
+
+		for i, discount := range roundTripped.Discounts {
+			if discount.Description != r.Discounts[i].Description ||
+				!sameCents(discount.Amount, r.Discounts[i].Amount) {
+				return false
+			}
+		}
+
+		// Scoring the round-tripped receipt must match scoring the
+		// original, which never went through the model.
+		if expected := entities.CountPoints(&r.Receipt); roundTripped.Points != expected {
+			t.Logf("points '%d' expected '%d'", roundTripped.Points, expected)
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/vimolicious/receipt-processor/data/retailers"
+)
+
+func TestRetailerIds(t *testing.T) {
+	registry := retailers.NewRegistry(retailers.DEFAULT_FUZZY_THRESHOLD)
+	if err := registry.Add(retailers.Retailer{Id: "walgreens", Name: "Walgreens"}); err != nil {
+		t.Fatal(err)
+	}
+
+	SetRetailers(registry)
+	defer SetRetailers(retailers.NewRegistry(retailers.DEFAULT_FUZZY_THRESHOLD))
+
+	cases := []struct {
+		retailer string
+		id       string
+	}{
+		{"WALGREENS #1234", "walgreens"},
+		{"Walgreen", "walgreens"},
+		{"Target", ""},
+	}
+
+	for _, c := range cases {
+		receipt, err := ReceiptModelToEntity(makeModel(c.retailer, "2022-01-01", "13:01", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if receipt.RetailerId != c.id {
+			t.Fatalf("Wrong retailer id for '%s': '%s' expected '%s'", c.retailer, receipt.RetailerId, c.id)
+		}
+
+		// The rules still score the name as written.
+		if receipt.Retailer != c.retailer {
+			t.Fatalf("Retailer name changed: '%s' expected '%s'", receipt.Retailer, c.retailer)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/models"
+)
+
+// Timezones decides how a receipt's wall-clock purchase time is interpreted
+// when it doesn't name its own time zone.
+type Timezones struct {
+	// Default applies to stores without their own time zone.
+	Default *time.Location
+	// Stores maps case-folded retailer names to their time zone.
+	Stores map[string]*time.Location
+}
+
+type timezonesFile struct {
+	Default string            `json:"default"`
+	Stores  map[string]string `json:"stores"`
+}
+
+var timezones = &Timezones{Default: time.UTC}
+
+// SetTimezones changes the time zones applied to receipts without a
+// timezone field. It should be called during startup.
+func SetTimezones(t *Timezones) {
+	timezones = t
+	models.SetStoreLocations(t.location)
+}
+
+// LoadTimezones reads store time zones from a JSON file shaped like:
+//
+//	{"default": "America/Chicago", "stores": {"Target": "America/Chicago"}}
+func LoadTimezones(path string) (*Timezones, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file timezonesFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	t := Timezones{
+		Default: time.UTC,
+		Stores:  make(map[string]*time.Location, len(file.Stores)),
+	}
+
+	if file.Default != "" {
+		if t.Default, err = time.LoadLocation(file.Default); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	for store, name := range file.Stores {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: store '%s': %w", path, store, err)
+		}
+		t.Stores[strings.ToLower(store)] = location
+	}
+
+	return &t, nil
+}
+
+func (t *Timezones) location(retailer string) *time.Location {
+	if location, ok := t.Stores[strings.ToLower(retailer)]; ok {
+		return location
+	}
+
+	if t.Default != nil {
+		return t.Default
+	}
+
+	return time.UTC
+}
+
+// parsePurchaseDateTime interprets the purchase date and time as a wall
+// clock reading in the receipt's time zone, rejecting times that don't exist
+// there, such as those skipped when daylight saving time starts.
+func parsePurchaseDateTime(date, clock string, location *time.Location) (time.Time, error) {
+	purchaseDateTime, err := time.ParseInLocation(
+		"2006-01-02 15:04", fmt.Sprintf("%s %s", date, clock), location,
+	)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// time.ParseInLocation moves nonexistent times forward by the size of
+	// the gap, so they no longer read the same.
+	if purchaseDateTime.Format("15:04") != clock {
+		return time.Time{}, fmt.Errorf(
+			"%w: purchaseTime %s doesn't exist in %s on %s",
+			ErrInvalidReceipt, clock, location, date,
+		)
+	}
+
+	return purchaseDateTime, nil
+}
@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vimolicious/receipt-processor/data/models"
+)
+
+func makeModel(retailer, date, clock string, timezone *string) *models.Receipt {
+	items := []models.Item{}
+	total := "1.00"
+	return &models.Receipt{
+		Items:        &items,
+		Retailer:     &retailer,
+		PurchaseDate: &date,
+		PurchaseTime: &clock,
+		Total:        &total,
+		Timezone:     timezone,
+	}
+}
+
+func TestTimezones(t *testing.T) {
+	chicago, _ := time.LoadLocation("America/Chicago")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+
+	SetTimezones(&Timezones{
+		Default: chicago,
+		Stores:  map[string]*time.Location{"lawson": tokyo},
+	})
+	defer SetTimezones(&Timezones{Default: time.UTC})
+
+	newYork := "America/New_York"
+
+	cases := []struct {
+		retailer string
+		timezone *string
+		location *time.Location
+	}{
+		{"Target", nil, chicago},
+		{"LAWSON", nil, tokyo},
+		{"Lawson", &newYork, nil},
+	}
+
+	for _, c := range cases {
+		receipt, err := ReceiptModelToEntity(makeModel(c.retailer, "2024-07-01", "14:30", c.timezone))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := c.location
+		if c.timezone != nil {
+			expected, _ = time.LoadLocation(*c.timezone)
+		}
+
+		if receipt.PurchaseDateTime.Location().String() != expected.String() {
+			t.Fatalf(
+				"%s: location '%s' expected '%s'",
+				c.retailer, receipt.PurchaseDateTime.Location(), expected,
+			)
+		}
+
+		// The wall clock reading is preserved, so the 2pm-4pm rule applies.
+		if receipt.PurchaseDateTime.Hour() != 14 || receipt.PurchaseDateTime.Minute() != 30 {
+			t.Fatalf("%s: wall clock changed to %s", c.retailer, receipt.PurchaseDateTime)
+		}
+
+		model, err := ReceiptEntityToModel(receipt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if model.Timezone == nil || *model.Timezone != expected.String() {
+			t.Fatalf("%s: time zone lost converting back to a model", c.retailer)
+		}
+	}
+}
+
+func TestDaylightSavingGap(t *testing.T) {
+	newYork := "America/New_York"
+
+	// Clocks in New York jumped from 02:00 to 03:00 on 2024-03-10.
+	_, err := ReceiptModelToEntity(makeModel("Target", "2024-03-10", "02:30", &newYork))
+	if !errors.Is(err, ErrInvalidReceipt) {
+		t.Fatalf("Expected ErrInvalidReceipt for a time in the DST gap, got: %v", err)
+	}
+
+	// 01:30 happened twice on 2024-11-03; either reading is accepted.
+	receipt, err := ReceiptModelToEntity(makeModel("Target", "2024-11-03", "01:30", &newYork))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.PurchaseDateTime.Format("15:04") != "01:30" {
+		t.Fatalf("Wrong wall clock time: %s", receipt.PurchaseDateTime)
+	}
+}
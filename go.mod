@@ -3,3 +3,5 @@ module github.com/vimolicious/receipt-processor
 go 1.22.0
 
 require github.com/google/uuid v1.6.0
+
+require golang.org/x/text v0.21.0
@@ -1,21 +1,194 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/vimolicious/receipt-processor/api/controllers"
+	"github.com/vimolicious/receipt-processor/api/middleware"
+	"github.com/vimolicious/receipt-processor/api/stream"
+	"github.com/vimolicious/receipt-processor/api/traffic"
+	"github.com/vimolicious/receipt-processor/data/analytics"
+	"github.com/vimolicious/receipt-processor/data/currency"
+	"github.com/vimolicious/receipt-processor/data/entities"
+	"github.com/vimolicious/receipt-processor/data/events"
+	"github.com/vimolicious/receipt-processor/data/models"
+	"github.com/vimolicious/receipt-processor/data/parse"
+	"github.com/vimolicious/receipt-processor/data/promotions"
 	"github.com/vimolicious/receipt-processor/data/repositories/inmemory"
+	"github.com/vimolicious/receipt-processor/data/rescore"
+	"github.com/vimolicious/receipt-processor/data/retailers"
+	"github.com/vimolicious/receipt-processor/data/transform"
 )
 
+const STREAM_BUFFER_SIZE = 1024
+const EVENT_RELAY_INTERVAL = 100 * time.Millisecond
+
+// DEFAULT_RESCORE_DIR holds rescoring checkpoints and the audit trail unless
+// RESCORE_DIR is set.
+const DEFAULT_RESCORE_DIR = "rescore"
+
+const CAPTURE_MAX_FILE_BYTES int64 = 100 << 20 // 100 MiB
+const CAPTURE_MAX_FILES = 5
+const CAPTURE_DEFAULT_MAX_BODY_BYTES = 64 << 10 // 64 KiB
+
+// withTrafficCapture wraps the handler with traffic capture when the
+// CAPTURE_FILE environment variable is set.
+func withTrafficCapture(handler http.Handler) http.Handler {
+	path := os.Getenv("CAPTURE_FILE")
+	if path == "" {
+		return handler
+	}
+
+	config := middleware.CaptureConfig{
+		SampleRate:    1,
+		MaxBodyBytes:  CAPTURE_DEFAULT_MAX_BODY_BYTES,
+		RedactHeaders: []string{"Authorization", "Cookie"},
+	}
+
+	if rate, err := strconv.ParseFloat(os.Getenv("CAPTURE_SAMPLE_RATE"), 64); err == nil {
+		config.SampleRate = rate
+	}
+
+	if maxBytes, err := strconv.Atoi(os.Getenv("CAPTURE_MAX_BODY_BYTES")); err == nil {
+		config.MaxBodyBytes = maxBytes
+	}
+
+	if fields := os.Getenv("CAPTURE_REDACT_FIELDS"); fields != "" {
+		config.RedactFields = strings.Split(fields, ",")
+	}
+
+	writer, err := traffic.NewRotatingWriter(path, CAPTURE_MAX_FILE_BYTES, CAPTURE_MAX_FILES)
+	if err != nil {
+		log.Fatalf("Couldn't open traffic capture file: %s", err.Error())
+	}
+
+	log.Printf("Capturing %.0f%% of traffic to %s\n", config.SampleRate*100, path)
+
+	return middleware.CaptureTraffic(handler, writer, config)
+}
+
 func main() {
+	if os.Getenv("STRING_POLICY") == "ascii" {
+		models.SetStringPolicy(models.ASCIIStringPolicy)
+	}
+
+	if path := os.Getenv("EXCHANGE_RATES_FILE"); path != "" {
+		rateTable, err := currency.LoadRateTable(path)
+		if err != nil {
+			log.Fatalf("Couldn't load exchange rates: %s", err.Error())
+		}
+		transform.SetExchangeRates(rateTable)
+	}
+
+	if path := os.Getenv("STORE_TIMEZONES_FILE"); path != "" {
+		timezones, err := transform.LoadTimezones(path)
+		if err != nil {
+			log.Fatalf("Couldn't load store time zones: %s", err.Error())
+		}
+		transform.SetTimezones(timezones)
+	}
+
+	retailerRegistry := retailers.NewRegistry(retailers.DEFAULT_FUZZY_THRESHOLD)
+	if path := os.Getenv("RETAILERS_FILE"); path != "" {
+		registry, err := retailers.LoadRegistry(path)
+		if err != nil {
+			log.Fatalf("Couldn't load retailers: %s", err.Error())
+		}
+		retailerRegistry = registry
+	}
+	transform.SetRetailers(retailerRegistry)
+
+	promotionEngine := promotions.NewEngine()
+	if path := os.Getenv("PROMOTIONS_FILE"); path != "" {
+		engine, err := promotions.LoadEngine(path)
+		if err != nil {
+			log.Fatalf("Couldn't load promotions: %s", err.Error())
+		}
+		promotionEngine = engine
+	}
+
+	textParser := parse.NewParser()
+	if path := os.Getenv("RECEIPT_LAYOUTS_FILE"); path != "" {
+		parser, err := parse.LoadParser(path)
+		if err != nil {
+			log.Fatalf("Couldn't load receipt layouts: %s", err.Error())
+		}
+		textParser = parser
+	}
+
+	dateWindow := models.DefaultDateWindow
+	if os.Getenv("ALLOW_FUTURE_RECEIPTS") == "true" {
+		dateWindow.AllowFuture = true
+	}
+	if days, err := strconv.Atoi(os.Getenv("MAX_RECEIPT_AGE_DAYS")); err == nil {
+		dateWindow.MaxAgeDays = days
+	}
+	models.SetDateWindow(dateWindow)
+
+	if tolerance, err := strconv.ParseFloat(os.Getenv("TOTAL_TOLERANCE"), 64); err == nil {
+		entities.SetTotalTolerance(tolerance)
+	}
+
 	receiptRepo := inmemory.NewInMemoryReceiptRepository()
-	receiptController := controllers.NewReceiptController(receiptRepo)
+	receiptRepo.EnableOutbox()
+
+	eventBroker := events.NewBroker()
+	eventRelay := events.NewRelay(receiptRepo, eventBroker, EVENT_RELAY_INTERVAL)
+	go eventRelay.Run(context.Background())
+
+	receiptHub := stream.NewHub(STREAM_BUFFER_SIZE)
+	eventBroker.Subscribe(receiptHub.HandleEvent, events.ReceiptAdded)
+
+	pointsAggregates := analytics.NewAggregates()
+	eventBroker.Subscribe(
+		pointsAggregates.HandleEvent,
+		events.ReceiptAdded, events.ReceiptImported, events.ReceiptRescored,
+	)
+
+	rescoreDir := os.Getenv("RESCORE_DIR")
+	if rescoreDir == "" {
+		rescoreDir = DEFAULT_RESCORE_DIR
+	}
+	rescoreManager := rescore.NewManager(receiptRepo, rescoreDir)
+	if _, err := rescoreManager.Resume(); err != nil {
+		log.Fatalf("Couldn't resume rescoring: %s", err.Error())
+	}
+
+	receiptController := controllers.NewReceiptController(
+		receiptRepo,
+		controllers.WithPromotions(promotionEngine),
+		controllers.WithTextParser(textParser),
+	)
+	streamController := controllers.NewStreamController(receiptHub)
+	openAPIController := controllers.NewOpenAPIController()
+	retailerController := controllers.NewRetailerController(retailerRegistry)
+	promotionController := controllers.NewPromotionController(promotionEngine)
+	simulationController := controllers.NewSimulationController(receiptRepo)
+	rescoreController := controllers.NewRescoreController(rescoreManager)
+	analyticsController := controllers.NewAnalyticsController(pointsAggregates)
+	exportController := controllers.NewExportController(receiptRepo)
+	importController := controllers.NewImportController(receiptRepo)
 
 	mux := http.NewServeMux()
 
 	receiptController.AddRouteHandlers(mux)
+	streamController.AddRouteHandlers(mux)
+	openAPIController.AddRouteHandlers(mux)
+	retailerController.AddRouteHandlers(mux)
+	promotionController.AddRouteHandlers(mux)
+	simulationController.AddRouteHandlers(mux)
+	rescoreController.AddRouteHandlers(mux)
+	analyticsController.AddRouteHandlers(mux)
+	exportController.AddRouteHandlers(mux)
+	importController.AddRouteHandlers(mux)
 
 	log.Println("Listening on port 8080...")
-	http.ListenAndServe(":8080", mux)
+	http.ListenAndServe(":8080", withTrafficCapture(mux))
 }